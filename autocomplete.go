@@ -0,0 +1,49 @@
+package hlx
+
+import (
+	"context"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Autocomplete returns up to limit documents matching the text typed so
+// far, best matches first. Every word must match, the last one as a prefix
+// unless prefix ends with a space. Prefix queries are much faster on
+// indexes created with WithPrefixIndexes.
+func (i *index[K]) Autocomplete(prefix string, limit int) ([]SearchResult[K], error) {
+	return i.AutocompleteContext(context.Background(), prefix, limit)
+}
+
+func (i *index[K]) AutocompleteContext(ctx context.Context, prefix string, limit int) ([]SearchResult[K], error) {
+	query := autocompleteQuery(prefix)
+	if query == "" {
+		return nil, nil
+	}
+
+	page, err := i.SearchWithOptionsContext(ctx, query, SearchOptions{Limit: limit, Mode: QueryFTS5})
+	return page.Results, err
+}
+
+// autocompleteQuery quotes every word of text, so it's matched literally,
+// and turns the last one into a prefix query.
+func autocompleteQuery(text string) string {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return ""
+	}
+
+	for n, w := range words {
+		words[n] = quoteTerm(w)
+	}
+	if last, _ := utf8.DecodeLastRuneInString(text); !unicode.IsSpace(last) {
+		words[len(words)-1] += "*"
+	}
+
+	return strings.Join(words, " ")
+}
+
+// quoteTerm returns s as an FTS5 string, matched as a phrase.
+func quoteTerm(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
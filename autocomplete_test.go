@@ -0,0 +1,68 @@
+package hlx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAutocompleteQuery(t *testing.T) {
+	assert.Equal(t, "", autocompleteQuery("   "))
+	assert.Equal(t, `"sea"*`, autocompleteQuery("sea"))
+	assert.Equal(t, `"full" "text" "sea"*`, autocompleteQuery(" full text sea"))
+	assert.Equal(t, `"full" "text"`, autocompleteQuery("full text "))
+	assert.Equal(t, `"NEAR(""x"*`, autocompleteQuery(`NEAR("x`))
+	assert.Equal(t, `"voilà"*`, autocompleteQuery("voilà"))
+	assert.Equal(t, `"Å"*`, autocompleteQuery("Å"))
+	assert.Equal(t, `"Мх"*`, autocompleteQuery("Мх"))
+	assert.Equal(t, `"voilà"`, autocompleteQuery("voilà\u00a0"))
+}
+
+func TestAutocomplete(t *testing.T) {
+	idx, err := NewIndex[TestDoc](":memory:", WithPrefixIndexes(2, 3, 4))
+	assert.NoError(t, err)
+
+	assert.NoError(t, idx.Insert(
+		TestDoc{Id: "1", Title: "search engines"},
+		TestDoc{Id: "2", Title: "seasonal search", Content: "search search"},
+		TestDoc{Id: "3", Title: "sea shells"},
+		TestDoc{Id: "4", Title: "full text search"},
+	))
+
+	results, err := idx.Autocomplete("sea", 10)
+	assert.NoError(t, err)
+	assert.Len(t, results, 4)
+
+	results, err = idx.Autocomplete("sea", 2)
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+	assert.Equal(t, "2", results[0].Document.Id)
+
+	results, err = idx.Autocomplete("full text se", 10)
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, "4", results[0].Document.Id)
+
+	assert.NoError(t, idx.Insert(TestDoc{Id: "5", Title: "voilàx"}))
+	results, err = idx.Autocomplete("voilà", 10)
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.NoError(t, idx.Delete("5"))
+
+	results, err = idx.Autocomplete("sea ", 10)
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, "3", results[0].Document.Id)
+
+	// FTS5 syntax is matched literally
+	results, err = idx.Autocomplete(`"sea -`, 10)
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+
+	results, err = idx.Autocomplete("", 10)
+	assert.NoError(t, err)
+	assert.Len(t, results, 0)
+
+	_, err = NewIndex[TestDoc](":memory:", WithPrefixIndexes(0))
+	assert.Error(t, err)
+}
@@ -0,0 +1,89 @@
+package hlx
+
+import (
+	"context"
+	"fmt"
+)
+
+// BulkMode selects how BulkInsert handles documents that fail to insert.
+type BulkMode int
+
+const (
+	// AllOrNothing inserts the documents only if every one of them
+	// succeeds.
+	AllOrNothing BulkMode = iota
+	// BestEffort inserts every document that succeeds, skipping the
+	// failed ones.
+	BestEffort
+)
+
+// DocumentError reports a document that failed to insert.
+type DocumentError struct {
+	// Index is the position of the document in the inserted batch.
+	Index int
+	Id    string
+	Err   error
+}
+
+func (e *DocumentError) Error() string {
+	return fmt.Sprintf("document %d (id %s): %v", e.Index, e.Id, e.Err)
+}
+
+func (e *DocumentError) Unwrap() error {
+	return e.Err
+}
+
+// BulkError is returned by BulkInsert when one or more documents failed.
+type BulkError struct {
+	// Committed is true if the documents that didn't fail were inserted.
+	Committed bool
+	Failures  []*DocumentError
+}
+
+func (e *BulkError) Error() string {
+	return fmt.Sprintf("%d documents failed to insert, first error: %v", len(e.Failures), e.Failures[0])
+}
+
+func (e *BulkError) Unwrap() []error {
+	errs := make([]error, len(e.Failures))
+	for n, f := range e.Failures {
+		errs[n] = f
+	}
+	return errs
+}
+
+// BulkInsert inserts docs in a single transaction, trying every
+// document and returning a *BulkError listing the ones that failed. With
+// AllOrNothing nothing is inserted if a document fails, with BestEffort
+// the remaining documents are.
+func (i *index[K]) BulkInsert(mode BulkMode, docs ...K) error {
+	return i.BulkInsertContext(context.Background(), mode, docs...)
+}
+
+func (i *index[K]) BulkInsertContext(ctx context.Context, mode BulkMode, docs ...K) error {
+	tx, err := i.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	ids, failures, err := i.insertDocs(ctx, tx, docs, false)
+	if err != nil {
+		return err
+	}
+	if len(failures) > 0 && mode == AllOrNothing {
+		return &BulkError{Failures: failures}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	for n, doc := range docs {
+		i.writeID(doc, ids[n])
+	}
+	if len(failures) > 0 {
+		return &BulkError{Committed: true, Failures: failures}
+	}
+
+	return nil
+}
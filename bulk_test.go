@@ -0,0 +1,91 @@
+package hlx
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// bulkDoc can hold values the SQLite driver can't store, to make inserts
+// fail.
+type bulkDoc struct {
+	Id    string
+	Title string
+	Extra any
+}
+
+func TestInsertIsAtomic(t *testing.T) {
+	idx, err := NewIndex[bulkDoc](":memory:")
+	assert.NoError(t, err)
+
+	err = idx.Insert(
+		bulkDoc{Id: "1", Title: "first"},
+		bulkDoc{Id: "2", Title: "second", Extra: struct{}{}},
+		bulkDoc{Id: "3", Title: "third"},
+	)
+	var docErr *DocumentError
+	assert.True(t, errors.As(err, &docErr))
+	assert.Equal(t, 1, docErr.Index)
+	assert.Equal(t, "2", docErr.Id)
+
+	_, err = idx.Get("1")
+	assert.ErrorIs(t, err, ErrDocumentNotFound)
+	_, err = idx.Get("3")
+	assert.ErrorIs(t, err, ErrDocumentNotFound)
+}
+
+func TestBulkInsert(t *testing.T) {
+	docs := []bulkDoc{
+		{Id: "1", Title: "first"},
+		{Id: "2", Title: "second", Extra: struct{}{}},
+		{Id: "3", Title: "third"},
+		{Id: "4", Title: "fourth", Extra: []int{1}},
+	}
+
+	t.Run("all or nothing", func(t *testing.T) {
+		idx, err := NewIndex[bulkDoc](":memory:")
+		assert.NoError(t, err)
+
+		err = idx.BulkInsert(AllOrNothing, docs...)
+		var bulkErr *BulkError
+		assert.True(t, errors.As(err, &bulkErr))
+		assert.False(t, bulkErr.Committed)
+		assert.Len(t, bulkErr.Failures, 2)
+		assert.Equal(t, 1, bulkErr.Failures[0].Index)
+		assert.Equal(t, 3, bulkErr.Failures[1].Index)
+
+		results, err := idx.Search("first OR third")
+		assert.NoError(t, err)
+		assert.Len(t, results, 0)
+	})
+
+	t.Run("best effort", func(t *testing.T) {
+		idx, err := NewIndex[bulkDoc](":memory:")
+		assert.NoError(t, err)
+
+		err = idx.BulkInsert(BestEffort, docs...)
+		var bulkErr *BulkError
+		assert.True(t, errors.As(err, &bulkErr))
+		assert.True(t, bulkErr.Committed)
+		assert.Len(t, bulkErr.Failures, 2)
+		assert.Equal(t, "2", bulkErr.Failures[0].Id)
+		assert.Equal(t, "4", bulkErr.Failures[1].Id)
+
+		results, err := idx.Search("first OR third")
+		assert.NoError(t, err)
+		assert.Len(t, results, 2)
+	})
+
+	t.Run("no failures", func(t *testing.T) {
+		idx, err := NewIndex[bulkDoc](":memory:")
+		assert.NoError(t, err)
+
+		err = idx.BulkInsert(AllOrNothing, docs[0], docs[2])
+		assert.NoError(t, err)
+
+		results, err := idx.Search("first OR third")
+		assert.NoError(t, err)
+		assert.Len(t, results, 2)
+	})
+}
@@ -0,0 +1,210 @@
+package hlx
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// timeFormat is how time.Time fields are stored: UTC, with a fixed number
+// of fractional digits, so that the text sorts like the times do.
+const timeFormat = "2006-01-02T15:04:05.000000000Z"
+
+// sqliteTimeFormat is how the SQLite driver stores time.Time values, used
+// to read documents written before time fields were converted.
+const sqliteTimeFormat = "2006-01-02 15:04:05.999999999-07:00"
+
+var (
+	timeType          = reflect.TypeFor[time.Time]()
+	valuerType        = reflect.TypeFor[driver.Valuer]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// fieldByIndex returns the nested field of the struct v with the given
+// index. Nil embedded pointers are allocated when alloc is set, otherwise
+// the zero value of the field is returned.
+func fieldByIndex(v reflect.Value, index []int, alloc bool) reflect.Value {
+	for n, x := range index {
+		if n > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				if !alloc {
+					return reflect.Zero(v.Type().Elem().FieldByIndex(index[n:]).Type)
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// encodeValue returns the value stored in the index for the struct field
+// f. Numbers, booleans, times and string slices are converted to text, so
+// they can be searched and parsed back by scanTarget.
+func encodeValue(f reflect.Value) (any, error) {
+	if f.Kind() == reflect.Pointer {
+		if f.IsNil() {
+			return nil, nil
+		}
+		f = f.Elem()
+	}
+
+	t := f.Type()
+	switch {
+	case t == timeType:
+		return f.Interface().(time.Time).UTC().Format(timeFormat), nil
+	case t.Implements(valuerType):
+		return f.Interface(), nil
+	case t.Implements(textMarshalerType):
+		b, err := f.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(b), err
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return f.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(f.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(f.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(f.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(f.Float(), 'f', -1, t.Bits()), nil
+	case reflect.Slice:
+		if t.Elem().Kind() != reflect.String {
+			break
+		}
+		if f.Len() == 0 {
+			return "", nil
+		}
+		b, err := json.Marshal(f.Interface())
+		return string(b), err
+	}
+
+	return f.Interface(), nil
+}
+
+// scanTarget returns the destination to scan a column into the struct
+// field f, and a function that stores the scanned value in the field, nil
+// if the value is scanned in place.
+func scanTarget(f reflect.Value) (any, func() error) {
+	addr := f.Addr()
+	t := f.Type()
+	if addr.Type().Implements(scannerType) {
+		return addr.Interface(), nil
+	}
+
+	// Pointer fields are NULL when nil, or hold the text of the value they
+	// point to.
+	if t.Kind() == reflect.Pointer && textDecoder(reflect.New(t.Elem()).Elem()) != nil {
+		var s sql.NullString
+		return &s, func() error {
+			if !s.Valid {
+				f.SetZero()
+				return nil
+			}
+			elem := reflect.New(t.Elem())
+			if err := decodeText(elem.Elem(), s.String); err != nil {
+				return err
+			}
+			f.Set(elem)
+			return nil
+		}
+	}
+
+	if textDecoder(f) == nil {
+		return addr.Interface(), nil
+	}
+
+	var s sql.NullString
+	return &s, func() error {
+		if !s.Valid {
+			f.SetZero()
+			return nil
+		}
+		return decodeText(f, s.String)
+	}
+}
+
+// decodeText stores in f the value parsed from the text s, which must be
+// decodable, see textDecoder. Empty text is the zero value of non-string
+// fields.
+func decodeText(f reflect.Value, s string) error {
+	if s == "" && f.Kind() != reflect.String {
+		f.SetZero()
+		return nil
+	}
+	return textDecoder(f)(s)
+}
+
+// textDecoder returns the function parsing the text stored by encodeValue
+// into f, nil for the fields scanned as is.
+func textDecoder(f reflect.Value) func(string) error {
+	addr := f.Addr()
+	switch {
+	case f.Type() == timeType:
+		return func(s string) error {
+			tm, err := time.Parse(time.RFC3339Nano, s)
+			if err != nil {
+				var legacyErr error
+				if tm, legacyErr = time.Parse(sqliteTimeFormat, s); legacyErr != nil {
+					return err
+				}
+			}
+			f.Set(reflect.ValueOf(tm.UTC()))
+			return nil
+		}
+	case addr.Type().Implements(textUnmarshalerType):
+		return func(s string) error {
+			return addr.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+		}
+	}
+	return decoderFor(f)
+}
+
+// decoderFor returns the function parsing the text stored by encodeValue
+// for fields of kind f.Kind(), nil for the kinds scanned as is.
+func decoderFor(f reflect.Value) func(string) error {
+	t := f.Type()
+	switch t.Kind() {
+	case reflect.Bool:
+		return func(s string) error {
+			b, err := strconv.ParseBool(s)
+			f.SetBool(b)
+			return err
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return func(s string) error {
+			n, err := strconv.ParseInt(s, 10, t.Bits())
+			f.SetInt(n)
+			return err
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return func(s string) error {
+			n, err := strconv.ParseUint(s, 10, t.Bits())
+			f.SetUint(n)
+			return err
+		}
+	case reflect.Float32, reflect.Float64:
+		return func(s string) error {
+			n, err := strconv.ParseFloat(s, t.Bits())
+			f.SetFloat(n)
+			return err
+		}
+	case reflect.Slice:
+		if t.Elem().Kind() != reflect.String {
+			return nil
+		}
+		return func(s string) error {
+			return json.Unmarshal([]byte(s), f.Addr().Interface())
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,205 @@
+package hlx
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type Meta struct {
+	Author  string
+	Created time.Time
+}
+
+type Stats struct {
+	Views int
+}
+
+type typedDoc struct {
+	Id string
+	Meta
+	*Stats
+	Title   string
+	Rating  float64
+	Draft   bool
+	Count   uint8
+	Tags    []string
+	private string
+}
+
+func TestFieldConversions(t *testing.T) {
+	idx, err := NewIndex[typedDoc](":memory:")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"id", "author", "created", "views", "title", "rating", "draft", "count", "tags"}, idx.Fields())
+
+	created := time.Date(2024, 3, 1, 12, 30, 0, 5, time.FixedZone("CET", 3600))
+	doc := typedDoc{
+		Id:      "1",
+		Meta:    Meta{Author: "alice", Created: created},
+		Stats:   &Stats{Views: 42},
+		Title:   "typed",
+		Rating:  4.5,
+		Draft:   true,
+		Count:   7,
+		Tags:    []string{"go", "sqlite"},
+		private: "not stored",
+	}
+	assert.NoError(t, idx.Insert(doc, typedDoc{Id: "2", Title: "zero"}))
+
+	got, err := idx.Get("1")
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", got.Author)
+	assert.True(t, created.Equal(got.Created))
+	assert.Equal(t, time.UTC, got.Created.Location())
+	assert.Equal(t, 42, got.Views)
+	assert.Equal(t, 4.5, got.Rating)
+	assert.True(t, got.Draft)
+	assert.Equal(t, uint8(7), got.Count)
+	assert.Equal(t, []string{"go", "sqlite"}, got.Tags)
+	assert.Empty(t, got.private)
+
+	zero, err := idx.Get("2")
+	assert.NoError(t, err)
+	assert.Equal(t, typedDoc{Id: "2", Title: "zero", Stats: &Stats{}}, zero)
+
+	for _, q := range []string{"author:alice", "views:42", "draft:true", "tags:sqlite", `rating:"4.5"`, "created:2024*"} {
+		count, err := idx.Count(q)
+		assert.NoError(t, err, q)
+		assert.Equal(t, 1, count, q)
+	}
+}
+
+func TestPointerFields(t *testing.T) {
+	type doc struct {
+		Id      string
+		Created *time.Time
+		Views   *int
+		Draft   *bool
+		Title   *string
+	}
+	idx, err := NewIndex[doc](":memory:")
+	assert.NoError(t, err)
+
+	created := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	views, draft, title := 42, false, "pointers"
+	full := doc{Id: "1", Created: &created, Views: &views, Draft: &draft, Title: &title}
+	assert.NoError(t, idx.Insert(full, doc{Id: "2"}))
+
+	got, err := idx.Get("1")
+	assert.NoError(t, err)
+	assert.Equal(t, full, got)
+
+	got, err = idx.Get("2")
+	assert.NoError(t, err)
+	assert.Equal(t, doc{Id: "2"}, got)
+
+	for _, f := range []Filter{Eq("views", 42), Eq("draft", false), Gte("created", created)} {
+		count, err := idx.CountWithOptions("", SearchOptions{Filters: []Filter{f}})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, count)
+	}
+	count, err := idx.CountWithOptions("", SearchOptions{Filters: []Filter{IsNull("created"), IsNull("views")}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestTimeFormatSorts(t *testing.T) {
+	a := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := a.Add(time.Millisecond)
+	c := time.Date(2024, 1, 1, 0, 0, 1, 0, time.FixedZone("", -3600))
+
+	fa, _ := encodeValue(reflect.ValueOf(a))
+	fb, _ := encodeValue(reflect.ValueOf(b))
+	fc, _ := encodeValue(reflect.ValueOf(c))
+	assert.Len(t, fa, len(fb.(string)))
+	assert.Less(t, fa, fb)
+	assert.Less(t, fb, fc)
+}
+
+func TestFloatFormat(t *testing.T) {
+	// Floats are stored without exponent, so the tokenizer keeps them whole.
+	for v, want := range map[any]string{
+		4.5:           "4.5",
+		-2.0:          "-2",
+		1e21:          "1000000000000000000000",
+		0.000001:      "0.000001",
+		float32(0.1):  "0.1",
+		float32(3e10): "30000000000",
+	} {
+		got, err := encodeValue(reflect.ValueOf(v))
+		assert.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+
+	type doc struct {
+		Id    string
+		Price float64
+	}
+	idx, err := NewIndex[doc](":memory:")
+	assert.NoError(t, err)
+	assert.NoError(t, idx.Insert(doc{Id: "1", Price: 1e21}, doc{Id: "2", Price: 0.000001}))
+	for _, q := range []string{"price:1000000000000000000000", `price:"0.000001"`} {
+		count, err := idx.Count(q)
+		assert.NoError(t, err, q)
+		assert.Equal(t, 1, count, q)
+	}
+	got, err := idx.Get("1")
+	assert.NoError(t, err)
+	assert.Equal(t, 1e21, got.Price)
+}
+
+func TestEmbeddedColumns(t *testing.T) {
+	t.Run("outer fields hide embedded ones", func(t *testing.T) {
+		type doc struct {
+			Meta
+			Id     string
+			Author string `hlx:"writer"`
+			Other  string `hlx:"author"`
+		}
+		idx, err := NewIndex[doc](":memory:")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"created", "id", "writer", "author"}, idx.Fields())
+	})
+
+	t.Run("id in an embedded struct", func(t *testing.T) {
+		type base struct {
+			Id string
+		}
+		type doc struct {
+			base
+			Title string
+		}
+		idx, err := NewIndex[*doc](":memory:")
+		assert.NoError(t, err)
+		d := &doc{Title: "embedded id"}
+		assert.NoError(t, idx.Insert(d))
+		got, err := idx.Get(d.Id)
+		assert.NoError(t, err)
+		assert.Equal(t, d, got)
+	})
+
+	t.Run("tagged embedded struct", func(t *testing.T) {
+		type doc struct {
+			Id   string
+			Meta `hlx:"-"`
+		}
+		idx, err := NewIndex[doc](":memory:")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"id"}, idx.Fields())
+	})
+
+	t.Run("duplicate columns", func(t *testing.T) {
+		type other struct {
+			Author string
+		}
+		type doc struct {
+			Id string
+			Meta
+			other
+		}
+		_, err := NewIndex[doc](":memory:")
+		assert.ErrorContains(t, err, `duplicate column "author"`)
+	})
+}
@@ -3,6 +3,7 @@ package hlx
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/jmoiron/sqlx"
@@ -17,20 +18,142 @@ func open(driver string, uri string) (*sqlx.DB, error) {
 	return db, nil
 }
 
-func initDatabase(ctx context.Context, db *sqlx.DB, uri string, fields []string, pragmas []string) (*sqlx.DB, error) {
-	for _, pragma := range pragmas {
+// initDatabase creates the tables of the index described by s, or checks
+// that the existing ones match it, migrating them if enabled in options.
+// The columns of s are reordered like the ones of an existing table.
+func initDatabase(ctx context.Context, db *sqlx.DB, uri string, s *schema, options *Options) (*sqlx.DB, error) {
+	for _, pragma := range options.pragmas {
 		_, err := db.ExecContext(ctx, pragma)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	q := `CREATE VIRTUAL TABLE IF NOT EXISTS fulltext_search USING FTS5(`
-	for _, k := range fields {
-		q += fmt.Sprintf(" %s,", strings.ToLower(k))
+	if err := initMeta(ctx, db); err != nil {
+		return db, err
 	}
-	q = q[:len(q)-1] + ");"
 
-	_, err := db.ExecContext(ctx, q)
+	stored, err := loadMeta(ctx, db, s.table)
+	if err != nil {
+		return db, err
+	}
+
+	if stored != nil {
+		stored.inherit(s)
+		mismatch := stored.diff(*s)
+		switch {
+		case mismatch == nil:
+			stored.sortColumns(s)
+		case options.autoMigrate:
+			if err := migrate(ctx, db, stored, *s); err != nil {
+				return db, fmt.Errorf("migrating table %s: %w", s.table, err)
+			}
+		default:
+			return db, mismatch
+		}
+	}
+
+	table := quoteIdent(s.table)
+	_, err = db.ExecContext(ctx, createTableQuery(table, *s))
+	if err != nil {
+		return db, err
+	}
+
+	if err := saveMeta(ctx, db, s.table, s.meta()); err != nil {
+		return db, err
+	}
+
+	if err := initIDs(ctx, db, *s); err != nil {
+		return db, err
+	}
+
+	// Persist the column weights as the default rank function, so
+	// ORDER BY rank honors them.
+	_, err = db.ExecContext(ctx,
+		fmt.Sprintf("INSERT INTO %[1]s(%[1]s, rank) VALUES('rank', ?)", table),
+		bm25(s.columns))
 	return db, err
 }
+
+// createTableQuery returns the statement creating the FTS5 table for s,
+// named table.
+func createTableQuery(table string, s schema) string {
+	q := fmt.Sprintf(`CREATE VIRTUAL TABLE IF NOT EXISTS %s USING FTS5(`, table)
+	for _, c := range s.columns {
+		q += " " + quoteIdent(c.name)
+		if c.unindexed {
+			q += " UNINDEXED"
+		}
+		q += ","
+	}
+	if !s.tokenizer.isZero() {
+		q += " tokenize = " + quoteString(s.tokenizer.String()) + ","
+	}
+	if len(s.prefixes) > 0 {
+		lengths := make([]string, len(s.prefixes))
+		for n, l := range s.prefixes {
+			lengths[n] = strconv.Itoa(l)
+		}
+		q += " prefix = " + quoteString(strings.Join(lengths, " ")) + ","
+	}
+	return q[:len(q)-1] + ");"
+}
+
+// initIDs creates the table mapping document ids to rowids, which keeps
+// ids unique and makes lookups by id fast. Indexes created before the
+// table existed are backfilled. Only the last inserted document of
+// duplicated ids is kept, the other copies are deleted.
+func initIDs(ctx context.Context, db *sqlx.DB, s schema) error {
+	var exists bool
+	err := db.GetContext(ctx, &exists,
+		"SELECT count(*) > 0 FROM sqlite_master WHERE type = 'table' AND name = ?", s.idsTable())
+	if err != nil || exists {
+		return err
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	ids := quoteIdent(s.idsTable())
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s (rid INTEGER PRIMARY KEY, id TEXT NOT NULL UNIQUE)", ids))
+	if err != nil {
+		return err
+	}
+
+	idCol, _ := s.columns.id()
+	table, id := quoteIdent(s.table), quoteIdent(idCol.name)
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(
+		"INSERT INTO %s (rid, id) SELECT max(rowid), %s FROM %s WHERE %[2]s IS NOT NULL GROUP BY %[2]s",
+		ids, id, table))
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(
+		"DELETE FROM %[1]s WHERE rowid IN (SELECT t.rowid FROM %[1]s AS t JOIN %[2]s AS i ON i.id = t.%[3]s WHERE i.rid != t.rowid)",
+		table, ids, id))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func bm25(cols columns) string {
+	weights := make([]string, len(cols))
+	for i, c := range cols {
+		weights[i] = strconv.FormatFloat(c.weight, 'f', -1, 64)
+	}
+	return fmt.Sprintf("bm25(%s)", strings.Join(weights, ", "))
+}
+
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func quoteString(s string) string {
+	return `'` + strings.ReplaceAll(s, `'`, `''`) + `'`
+}
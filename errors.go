@@ -1,5 +1,83 @@
 package hlx
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 var ErrDocumentNotFound = fmt.Errorf("document not found")
+
+var ErrInvalidCursor = fmt.Errorf("invalid cursor")
+
+var ErrDuplicateID = fmt.Errorf("duplicate document id")
+
+var ErrSchemaMismatch = fmt.Errorf("schema mismatch")
+
+var ErrMissingID = fmt.Errorf("document id is missing")
+
+var ErrQuerySyntax = fmt.Errorf("query syntax error")
+
+// SchemaMismatchError is returned by NewIndex when the document type no
+// longer matches the schema of an existing index. It matches
+// ErrSchemaMismatch with errors.Is.
+type SchemaMismatchError struct {
+	Table string
+	// Added are the columns of the document type missing from the index.
+	Added []string
+	// Removed are the columns of the index missing from the document type.
+	Removed []string
+	// Changed are the columns whose options, like unindexed, differ.
+	Changed []string
+	// Options are the table options that differ, "tokenizer" or "prefix".
+	Options []string
+}
+
+func (e *SchemaMismatchError) Error() string {
+	var details []string
+	if len(e.Added) > 0 {
+		details = append(details, "added columns: "+strings.Join(e.Added, ", "))
+	}
+	if len(e.Removed) > 0 {
+		details = append(details, "removed columns: "+strings.Join(e.Removed, ", "))
+	}
+	if len(e.Changed) > 0 {
+		details = append(details, "changed columns: "+strings.Join(e.Changed, ", "))
+	}
+	if len(e.Options) > 0 {
+		details = append(details, "changed options: "+strings.Join(e.Options, ", "))
+	}
+	return fmt.Sprintf("%v for table %s (%s)", ErrSchemaMismatch, e.Table, strings.Join(details, "; "))
+}
+
+func (e *SchemaMismatchError) Is(target error) bool {
+	return target == ErrSchemaMismatch
+}
+
+// QuerySyntaxError is returned by the search methods when SQLite rejects
+// an FTS5 query. It matches ErrQuerySyntax with errors.Is, and unwraps to
+// the error returned by the database driver.
+type QuerySyntaxError struct {
+	// Query is the FTS5 query that was rejected.
+	Query string
+	// Token is the part of the query the error was detected at, empty
+	// when the query ended unexpectedly.
+	Token string
+	// Offset is the byte offset of Token in Query, the length of Query
+	// when it ended unexpectedly, or -1 when the token wasn't found.
+	Offset int
+	// Hint is a human-readable description of the error.
+	Hint string
+	Err  error
+}
+
+func (e *QuerySyntaxError) Error() string {
+	return fmt.Sprintf("%v at offset %d: %s", ErrQuerySyntax, e.Offset, e.Hint)
+}
+
+func (e *QuerySyntaxError) Is(target error) bool {
+	return target == ErrQuerySyntax
+}
+
+func (e *QuerySyntaxError) Unwrap() error {
+	return e.Err
+}
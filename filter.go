@@ -0,0 +1,269 @@
+package hlx
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Filter is a condition on the stored value of a field, set in
+// SearchOptions.Filters. Filters are applied in the same query as the
+// full-text match, so limits, cursors and counts only consider the
+// documents matching both. They work on any field, and are the way to
+// select documents by their unindexed fields.
+//
+// Values are compared to the stored text, converted like document fields
+// are, so Eq("views", 42) matches the documents whose Views field is 42.
+type Filter interface {
+	// where returns the predicate and its arguments. column returns the
+	// quoted name of a field.
+	where(column func(string) (string, error)) (string, []any, error)
+}
+
+// Eq matches the documents whose field equals value. A nil value matches
+// the documents whose field is NULL, like IsNull.
+func Eq(field string, value any) Filter {
+	return eqFilter{field: field, value: value}
+}
+
+// In matches the documents whose field equals one of values, or is NULL
+// when one of values is nil. With no values it matches nothing.
+func In(field string, values ...any) Filter {
+	return inFilter{field: field, values: values}
+}
+
+// Gt matches the documents whose field is greater than value. Numeric
+// values compare the field as a number, other values compare the stored
+// text, which sorts like the values do for strings and times.
+func Gt(field string, value any) Filter {
+	return rangeFilter{field: field, gt: value}
+}
+
+// Gte matches the documents whose field is greater than or equal to
+// value, compared like Gt does.
+func Gte(field string, value any) Filter {
+	return rangeFilter{field: field, gte: value}
+}
+
+// Lt matches the documents whose field is less than value, compared like
+// Gt does.
+func Lt(field string, value any) Filter {
+	return rangeFilter{field: field, lt: value}
+}
+
+// Lte matches the documents whose field is less than or equal to value,
+// compared like Gt does.
+func Lte(field string, value any) Filter {
+	return rangeFilter{field: field, lte: value}
+}
+
+// IsNull matches the documents whose field is NULL, like nil pointer
+// fields. Empty strings aren't NULL.
+func IsNull(field string) Filter {
+	return eqFilter{field: field}
+}
+
+// Contains matches the documents whose []string field has value as one
+// of its elements.
+func Contains(field, value string) Filter {
+	return containsFilter{field: field, value: value}
+}
+
+// Not matches the documents not matching f. Not(Eq(field, value)) doesn't
+// match NULL fields, combine it with IsNull to include them.
+func Not(f Filter) Filter {
+	return notFilter{f: f}
+}
+
+// AllOf matches the documents matching all of filters.
+func AllOf(filters ...Filter) Filter {
+	return filterGroup{op: "AND", filters: filters}
+}
+
+// AnyOf matches the documents matching any of filters.
+func AnyOf(filters ...Filter) Filter {
+	return filterGroup{op: "OR", filters: filters}
+}
+
+// eqFilter matches the documents whose field equals value, or is NULL
+// when value is nil.
+type eqFilter struct {
+	field string
+	value any
+}
+
+func (f eqFilter) where(column func(string) (string, error)) (string, []any, error) {
+	col, err := column(f.field)
+	if err != nil {
+		return "", nil, err
+	}
+	if f.value == nil {
+		return col + " IS NULL", nil, nil
+	}
+	v, err := filterValue(f.value)
+	return col + " = ?", []any{v}, err
+}
+
+// inFilter matches the documents whose field equals one of values, nil
+// values matching NULL fields.
+type inFilter struct {
+	field  string
+	values []any
+}
+
+func (f inFilter) where(column func(string) (string, error)) (string, []any, error) {
+	col, err := column(f.field)
+	if err != nil {
+		return "", nil, err
+	}
+	var preds []string
+	var args []any
+	for _, value := range f.values {
+		if value == nil {
+			preds = append(preds, col+" IS NULL")
+			continue
+		}
+		v, err := filterValue(value)
+		if err != nil {
+			return "", nil, err
+		}
+		args = append(args, v)
+	}
+	if len(args) > 0 {
+		preds = append(preds, col+" IN (?"+strings.Repeat(", ?", len(args)-1)+")")
+	}
+	if len(preds) == 0 {
+		return "0", nil, nil
+	}
+	return "(" + strings.Join(preds, " OR ") + ")", args, nil
+}
+
+// rangeFilter matches the documents whose field is within the bounds that
+// are not nil. Numeric bounds compare the field as a number, other bounds
+// compare the stored text, which sorts like the values do for strings and
+// times.
+type rangeFilter struct {
+	field            string
+	gt, gte, lt, lte any
+}
+
+func (f rangeFilter) where(column func(string) (string, error)) (string, []any, error) {
+	col, err := column(f.field)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var preds []string
+	var args []any
+	for _, bound := range []struct {
+		op    string
+		value any
+	}{{">", f.gt}, {">=", f.gte}, {"<", f.lt}, {"<=", f.lte}} {
+		if bound.value == nil {
+			continue
+		}
+		if isNumber(bound.value) {
+			preds = append(preds, fmt.Sprintf("CAST(%s AS REAL) %s ?", col, bound.op))
+			args = append(args, reflect.ValueOf(bound.value).Convert(reflect.TypeFor[float64]()).Interface())
+			continue
+		}
+		v, err := filterValue(bound.value)
+		if err != nil {
+			return "", nil, err
+		}
+		preds = append(preds, fmt.Sprintf("%s %s ?", col, bound.op))
+		args = append(args, v)
+	}
+	if len(preds) == 0 {
+		return "", nil, fmt.Errorf("range on %s without bounds", f.field)
+	}
+	return "(" + strings.Join(preds, " AND ") + ")", args, nil
+}
+
+// containsFilter matches the documents whose []string field, stored as a
+// JSON array, has value as an element.
+type containsFilter struct {
+	field string
+	value string
+}
+
+func (f containsFilter) where(column func(string) (string, error)) (string, []any, error) {
+	col, err := column(f.field)
+	if err != nil {
+		return "", nil, err
+	}
+	// Empty slices are stored as empty strings, which aren't JSON.
+	pred := fmt.Sprintf("EXISTS (SELECT 1 FROM json_each(CASE WHEN json_valid(%[1]s) THEN %[1]s ELSE '[]' END) WHERE value = ?)", col)
+	return pred, []any{f.value}, nil
+}
+
+// notFilter matches the documents not matching f.
+type notFilter struct {
+	f Filter
+}
+
+func (f notFilter) where(column func(string) (string, error)) (string, []any, error) {
+	if f.f == nil {
+		return "", nil, fmt.Errorf("nil filter")
+	}
+	pred, args, err := f.f.where(column)
+	return "NOT (" + pred + ")", args, err
+}
+
+// filterGroup matches the documents matching all of filters with the AND
+// operator, or any of them with OR.
+type filterGroup struct {
+	op      string
+	filters []Filter
+}
+
+func (f filterGroup) where(column func(string) (string, error)) (string, []any, error) {
+	if len(f.filters) == 0 {
+		if f.op == "AND" {
+			return "1", nil, nil
+		}
+		return "0", nil, nil
+	}
+	preds := make([]string, len(f.filters))
+	var args []any
+	for n, filter := range f.filters {
+		if filter == nil {
+			return "", nil, fmt.Errorf("nil filter")
+		}
+		pred, a, err := filter.where(column)
+		if err != nil {
+			return "", nil, err
+		}
+		preds[n] = "(" + pred + ")"
+		args = append(args, a...)
+	}
+	return "(" + strings.Join(preds, " "+f.op+" ") + ")", args, nil
+}
+
+// filterColumn returns the expression of the stored text of field.
+func (i *index[K]) filterColumn(field string) (string, error) {
+	if i.columnIndex(field) < 0 {
+		return "", fmt.Errorf("unknown field %q", field)
+	}
+	if i.idKind == idInt && field == i.idColumn {
+		// Integer ids are stored as integers, the rowid of the document,
+		// which never equal the text the filter values are converted to.
+		return fmt.Sprintf("CAST(%s AS TEXT)", quoteIdent(field)), nil
+	}
+	return quoteIdent(field), nil
+}
+
+// where returns the SQL predicate matching all filters.
+func (i *index[K]) where(filters []Filter) (string, []any, error) {
+	return AllOf(filters...).where(i.filterColumn)
+}
+
+// filterValue returns value as it's stored in the index, see encodeValue.
+func filterValue(value any) (any, error) {
+	return encodeValue(reflect.ValueOf(value))
+}
+
+func isNumber(value any) bool {
+	k := reflect.ValueOf(value).Kind()
+	return isIntKind(k) || k == reflect.Float32 || k == reflect.Float64
+}
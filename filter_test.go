@@ -0,0 +1,139 @@
+package hlx
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilters(t *testing.T) {
+	idx := newArticleIndex(t)
+
+	tests := []struct {
+		name    string
+		query   string
+		filters []Filter
+		want    []string
+	}{
+		{"eq", "sqlite", []Filter{Eq("status", "published")}, []string{"1", "2"}},
+		{"eq int", "", []Filter{Eq("views", 120)}, []string{"3"}},
+		{"in", "", []Filter{In("views", 10, 50)}, []string{"2", "4"}},
+		{"in empty", "", []Filter{In("views")}, nil},
+		{"in nil", "", []Filter{In("editor", "bob", nil)}, []string{"2", "3"}},
+		{"range int", "", []Filter{Gte("views", 50), Lt("views", 900)}, []string{"2", "3"}},
+		{"range numeric", "", []Filter{Gt("views", 100)}, []string{"1", "3"}},
+		{"range time", "", []Filter{Gt("published", jan(1)), Lte("published", jan(10))}, []string{"2", "3"}},
+		{"range time zone", "", []Filter{Lt("published", jan(5).In(time.FixedZone("CET", 3600)))}, []string{"1"}},
+		{"null", "", []Filter{IsNull("editor")}, []string{"2", "3"}},
+		{"eq nil", "", []Filter{Eq("editor", nil)}, []string{"2", "3"}},
+		{"contains", "", []Filter{Contains("tags", "go")}, []string{"3"}},
+		{"contains empty slice", "", []Filter{Not(Contains("tags", "sqlite"))}, []string{"4"}},
+		{"not", "sqlite", []Filter{Not(Eq("status", "draft"))}, []string{"1", "2"}},
+		{"any of", "", []Filter{AnyOf(Eq("status", "draft"), Gt("views", 500))}, []string{"1", "3"}},
+		{"all of", "", []Filter{AllOf(Eq("status", "published"), Lt("views", 100))}, []string{"2", "4"}},
+		{"match and filters", "go", []Filter{Eq("status", "published")}, []string{"4"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := idx.SearchWithOptions(tt.query, SearchOptions{Filters: tt.filters})
+			assert.NoError(t, err)
+			got := articleIDs(page.Results)
+			if tt.query != "" {
+				assert.ElementsMatch(t, tt.want, got)
+			} else {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFilterIntegerIDs(t *testing.T) {
+	type doc struct {
+		Id    int
+		Title string
+	}
+	idx, err := NewIndex[doc](":memory:")
+	assert.NoError(t, err)
+	assert.NoError(t, idx.Insert(doc{Id: 10, Title: "ten"}, doc{Id: 20, Title: "twenty"}, doc{Id: 30, Title: "thirty"}))
+
+	for _, f := range []Filter{Eq("id", 10), Eq("id", "10"), In("id", 10, 40), Lt("id", 20)} {
+		page, err := idx.SearchWithOptions("", SearchOptions{Filters: []Filter{f}})
+		assert.NoError(t, err)
+		if assert.Len(t, page.Results, 1) {
+			assert.Equal(t, 10, page.Results[0].Document.Id)
+		}
+	}
+
+	var req SearchRequest
+	assert.NoError(t, json.Unmarshal([]byte(`{"term": {"id": 20}}`), &req))
+	page, err := idx.Execute(req)
+	assert.NoError(t, err)
+	if assert.Len(t, page.Results, 1) {
+		assert.Equal(t, 20, page.Results[0].Document.Id)
+	}
+}
+
+func TestFilterErrors(t *testing.T) {
+	idx := newArticleIndex(t)
+
+	for _, f := range []Filter{Eq("missing", 1), AnyOf(nil), Not(nil), AllOf(Gt("views", 1), Lt("missing", 2))} {
+		_, err := idx.SearchWithOptions("sqlite", SearchOptions{Filters: []Filter{f}})
+		assert.Error(t, err)
+	}
+
+	// An empty query without filters still matches nothing.
+	page, err := idx.SearchWithOptions("", SearchOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, page.Results)
+}
+
+func TestFilterPagination(t *testing.T) {
+	idx := newArticleIndex(t)
+	opts := SearchOptions{Limit: 1, Filters: []Filter{Eq("status", "published")}}
+
+	var ids []string
+	for {
+		page, err := idx.SearchWithOptions("sqlite OR go", opts)
+		assert.NoError(t, err)
+		ids = append(ids, articleIDs(page.Results)...)
+		if page.NextCursor == "" {
+			break
+		}
+		opts.Cursor = page.NextCursor
+	}
+	assert.ElementsMatch(t, []string{"1", "2", "4"}, ids)
+
+	opts = SearchOptions{Limit: 2, Offset: 1, Filters: []Filter{Gte("views", 50)}}
+	page, err := idx.SearchWithOptions("", opts)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2", "3"}, articleIDs(page.Results))
+}
+
+func TestCountWithOptions(t *testing.T) {
+	idx := newArticleIndex(t)
+
+	count, err := idx.CountWithOptions("sqlite", SearchOptions{Filters: []Filter{Eq("status", "published")}})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	count, err = idx.CountWithOptions("", SearchOptions{Filters: []Filter{Contains("tags", "sqlite")}})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	count, err = idx.CountWithOptions("sqlite -go", SearchOptions{Mode: QuerySimple, Filters: []Filter{Gt("views", 100)}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	count, err = idx.CountWithOptions("sqlite", SearchOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	_, err = idx.CountWithOptions("sqlite", SearchOptions{Filters: []Filter{Eq("missing", 1)}})
+	assert.Error(t, err)
+
+	_, err = idx.CountWithOptions("sqlite AND", SearchOptions{Filters: []Filter{Eq("status", "draft")}})
+	assert.ErrorIs(t, err, ErrQuerySyntax)
+}
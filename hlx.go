@@ -3,18 +3,28 @@ package hlx
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"iter"
 	"reflect"
 	"strings"
 
-	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 )
 
 type Options struct {
-	DB      *sqlx.DB
-	driver  string
-	pragmas []string
+	DB          *sqlx.DB
+	driver      string
+	pragmas     []string
+	weights     map[string]float64
+	table       string
+	tokenizer   Tokenizer
+	prefixes    []int
+	autoMigrate bool
+	idGenerator any
+	nameMapper  func(string) string
+	queryMode   QueryMode
 }
 
 type Option func(*Options)
@@ -37,24 +47,156 @@ func WithPragmas(pragmas []string) Option {
 	}
 }
 
-const insertQuery = "INSERT INTO fulltext_search (%s) VALUES (%s)"
+// WithWeights sets the bm25 weight of the given columns, overriding the
+// weights set with struct tags. Columns not present in the map keep their
+// weight.
+func WithWeights(weights map[string]float64) Option {
+	return func(o *Options) {
+		o.weights = weights
+	}
+}
+
+// DefaultTableName is the name of the FTS5 table used when no other name
+// is set with WithTableName.
+const DefaultTableName = "fulltext_search"
+
+// WithTableName sets the name of the FTS5 table storing the documents, so
+// several indexes, of the same or different document types, can share a
+// database. Defaults to DefaultTableName.
+func WithTableName(name string) Option {
+	return func(o *Options) {
+		o.table = name
+	}
+}
+
+// WithTokenizer sets the tokenizer of the index. Existing indexes opened
+// without it keep the tokenizer they were created with, and opening them
+// with a different one fails with ErrSchemaMismatch, unless WithAutoMigrate
+// is set to rebuild them with the new tokenizer.
+func WithTokenizer(t Tokenizer) Option {
+	return func(o *Options) {
+		o.tokenizer = t
+	}
+}
+
+// WithPrefixIndexes adds FTS5 prefix indexes for the given prefix
+// lengths, in characters. They speed up prefix queries like "sea*" that
+// are at least as long as the indexed prefixes, at the cost of a larger
+// index. Like with WithTokenizer, existing indexes opened without it keep
+// their prefix indexes, and opening them with different lengths fails with
+// ErrSchemaMismatch unless WithAutoMigrate is set.
+func WithPrefixIndexes(lengths ...int) Option {
+	return func(o *Options) {
+		o.prefixes = lengths
+	}
+}
+
+// WithAutoMigrate rebuilds an existing index when its schema no longer
+// matches the document type, instead of failing with ErrSchemaMismatch.
+// Rows are copied to the new table matching columns by name: removed
+// columns are dropped and added ones are left empty, except a renamed id
+// column, which keeps the document ids.
+func WithAutoMigrate() Option {
+	return func(o *Options) {
+		o.autoMigrate = true
+	}
+}
+
+// WithNameMapper sets the function mapping struct field names to column
+// names, for fields without a name in their hlx or db struct tag. The
+// default mapper is strings.ToLower, the sqlx default. Column names are
+// always lowercased.
+func WithNameMapper(mapper func(string) string) Option {
+	return func(o *Options) {
+		o.nameMapper = mapper
+	}
+}
+
+// WithQueryMode sets how the queries passed to the search methods and
+// Count are interpreted, unless SearchOptions.Mode says otherwise.
+// Indexes queried with text typed by users can use QuerySimple, so
+// queries never fail with a syntax error. Defaults to QueryFTS5.
+func WithQueryMode(mode QueryMode) Option {
+	return func(o *Options) {
+		o.queryMode = mode
+	}
+}
+
+const insertQuery = "INSERT INTO %s (rowid, %s) VALUES (?, %s)"
 
+// selectRowIDQuery returns the rowid of the document with the given id.
+const selectRowIDQuery = "SELECT rid FROM %s WHERE id = ?"
+
+// insertIDQuery allocates the rowid of a new document, inserting nothing
+// if the id is already in use. A nil rowid picks the next free one.
+const insertIDQuery = "INSERT OR IGNORE INTO %s (rid, id) VALUES (?, ?)"
+
+// allocIDQuery allocates the rowid of a new document with an integer id,
+// which is the rowid itself.
+const allocIDQuery = "INSERT INTO %[1]s (rid, id) SELECT n, n FROM (SELECT coalesce(max(rid), 0) + 1 AS n FROM %[1]s)"
+
+// Index is a full-text index of documents of type K.
+//
+// Every operation has a Context variant that aborts the underlying SQLite
+// queries when the context is canceled or its deadline expires. The
+// variants without a context use context.Background().
 type Index[K any] interface {
 	Search(query string) ([]K, error)
+	SearchContext(ctx context.Context, query string) ([]K, error)
+	SearchRanked(query string) ([]SearchResult[K], error)
+	SearchRankedContext(ctx context.Context, query string) ([]SearchResult[K], error)
+	SearchIter(query string) iter.Seq2[K, error]
+	SearchIterContext(ctx context.Context, query string) iter.Seq2[K, error]
+	SearchWithOptions(query string, opts SearchOptions) (SearchPage[K], error)
+	SearchWithOptionsContext(ctx context.Context, query string, opts SearchOptions) (SearchPage[K], error)
 	Insert(doc ...K) error
+	InsertContext(ctx context.Context, doc ...K) error
+	InsertReturningIDs(doc ...K) ([]string, error)
+	InsertReturningIDsContext(ctx context.Context, doc ...K) ([]string, error)
+	BulkInsert(mode BulkMode, doc ...K) error
+	BulkInsertContext(ctx context.Context, mode BulkMode, doc ...K) error
+	Update(doc K) error
+	UpdateContext(ctx context.Context, doc K) error
+	Upsert(doc ...K) error
+	UpsertContext(ctx context.Context, doc ...K) error
 	Delete(id string) error
+	DeleteContext(ctx context.Context, id string) error
 	Get(id string) (K, error)
+	GetContext(ctx context.Context, id string) (K, error)
+	GetMany(ids ...string) ([]K, error)
+	GetManyContext(ctx context.Context, ids ...string) ([]K, error)
+	Exists(id string) (bool, error)
+	ExistsContext(ctx context.Context, id string) (bool, error)
+	Count(query string) (int, error)
+	CountContext(ctx context.Context, query string) (int, error)
+	CountWithOptions(query string, opts SearchOptions) (int, error)
+	CountWithOptionsContext(ctx context.Context, query string, opts SearchOptions) (int, error)
+	All() iter.Seq2[K, error]
+	AllContext(ctx context.Context) iter.Seq2[K, error]
+	Autocomplete(prefix string, limit int) ([]SearchResult[K], error)
+	AutocompleteContext(ctx context.Context, prefix string, limit int) ([]SearchResult[K], error)
+	SearchUserInput(input string) ([]SearchResult[K], error)
+	SearchUserInputContext(ctx context.Context, input string) ([]SearchResult[K], error)
+	Execute(req SearchRequest) (SearchPage[K], error)
+	ExecuteContext(ctx context.Context, req SearchRequest) (SearchPage[K], error)
 	Fields() []string
 }
 
 type index[K any] struct {
-	fields     fields
+	table      string
+	idsTable   string
+	fields     []string
+	columns    columns
+	idColumn   string
+	idKind     idKind
+	idGen      func(K) string
+	queryMode  QueryMode
+	selectCols string
 	db         *sqlx.DB
 	insertStmt *sql.Stmt
+	idStmt     *sql.Stmt
 }
 
-type fields []string
-
 var DefaultPragmas = []string{
 	"PRAGMA journal_mode=WAL",
 	"PRAGMA synchronous=NORMAL",
@@ -65,7 +207,7 @@ var DefaultPragmas = []string{
 }
 
 func NewIndex[K any](uri string, opts ...Option) (Index[K], error) {
-	options := &Options{pragmas: DefaultPragmas}
+	options := &Options{pragmas: DefaultPragmas, table: DefaultTableName}
 	for _, opt := range opts {
 		opt(options)
 	}
@@ -74,50 +216,96 @@ func NewIndex[K any](uri string, opts ...Option) (Index[K], error) {
 		options.driver = "sqlite3"
 	}
 
-	f := make(fields, 0)
-	var zero K
-	v := reflect.ValueOf(zero)
-	if v.Kind() == reflect.Ptr {
-		v = v.Elem()
+	if options.table == "" {
+		return nil, fmt.Errorf("empty table name")
 	}
-	t := v.Type()
 
-	idAdded := false
-	for i := range t.NumField() {
-		field := t.Field(i)
-		l := strings.ToLower(field.Name)
-		f = append(f, l)
-		if l == "id" {
-			idAdded = true
-		}
+	t := reflect.TypeFor[K]()
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("document type %s is not a struct", t)
 	}
 
-	if !idAdded {
+	cols, err := parseColumns(t, options.nameMapper)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cols.setWeights(options.weights); err != nil {
+		return nil, err
+	}
+
+	idCol, ok := cols.id()
+	if !ok {
 		return nil, fmt.Errorf("Id field is missing")
 	}
+	idType := t.FieldByIndex(idCol.index).Type
+	idKind, err := idKindOf(idType)
+	if err != nil {
+		return nil, err
+	}
+
+	var idGen func(K) string
+	switch gen := options.idGenerator.(type) {
+	case nil:
+		if idKind == idString || idType == uuidType {
+			idGen = UUIDv4[K]
+		}
+	case func(K) string:
+		if idKind == idInt {
+			return nil, fmt.Errorf("id generators are not supported with integer ids")
+		}
+		idGen = gen
+	default:
+		return nil, fmt.Errorf("id generator %T does not match the document type %s", gen, reflect.TypeFor[K]())
+	}
+
+	for _, n := range options.prefixes {
+		if n < 1 || n > 999 {
+			return nil, fmt.Errorf("invalid prefix index length %d", n)
+		}
+	}
+
+	s := schema{
+		table:     options.table,
+		columns:   cols,
+		tokenizer: options.tokenizer,
+		prefixes:  options.prefixes,
+	}
 
 	var db *sqlx.DB
-	var err error
 	if options.DB != nil {
-		db, err = initDatabase(context.Background(), options.DB, uri, f, options.pragmas)
+		db, err = initDatabase(context.Background(), options.DB, uri, &s, options)
+		if err != nil {
+			return nil, err
+		}
 	} else {
 		db, err = open(options.driver, uri)
 		if err != nil {
 			return nil, err
 		}
-		db, err = initDatabase(context.Background(), db, uri, f, options.pragmas)
+		db, err = initDatabase(context.Background(), db, uri, &s, options)
 		if err != nil {
 			return nil, err
 		}
 	}
 
+	f := s.columns.names()
+	quoted := make([]string, len(f))
+	for i, name := range f {
+		quoted[i] = quoteIdent(name)
+	}
+
 	q := []string{}
 	for range f {
 		q = append(q, "?")
 	}
 	pholder := strings.Join(q, ",")
 	iquery := fmt.Sprintf(insertQuery,
-		strings.Join(f, ","),
+		quoteIdent(s.table),
+		strings.Join(quoted, ","),
 		pholder)
 
 	stmt, err := db.Prepare(iquery)
@@ -125,7 +313,25 @@ func NewIndex[K any](uri string, opts ...Option) (Index[K], error) {
 		return nil, err
 	}
 
-	return &index[K]{fields: f, db: db, insertStmt: stmt}, nil
+	idStmt, err := db.Prepare(fmt.Sprintf(insertIDQuery, quoteIdent(s.idsTable())))
+	if err != nil {
+		return nil, err
+	}
+
+	return &index[K]{
+		table:      quoteIdent(s.table),
+		idsTable:   quoteIdent(s.idsTable()),
+		fields:     f,
+		columns:    s.columns,
+		idColumn:   idCol.name,
+		idKind:     idKind,
+		idGen:      idGen,
+		queryMode:  options.queryMode,
+		selectCols: strings.Join(quoted, ", "),
+		db:         db,
+		insertStmt: stmt,
+		idStmt:     idStmt,
+	}, nil
 }
 
 func (i *index[K]) Fields() []string {
@@ -133,66 +339,466 @@ func (i *index[K]) Fields() []string {
 }
 
 func (i *index[K]) Get(id string) (K, error) {
+	return i.GetContext(context.Background(), id)
+}
+
+func (i *index[K]) GetContext(ctx context.Context, id string) (K, error) {
 	var doc K
-	rows, err := i.db.Queryx("SELECT * FROM fulltext_search WHERE id = ?", id)
+	q := fmt.Sprintf("SELECT %s FROM %s WHERE rowid = (%s)", i.selectCols, i.table, i.selectRowID())
+	var arg any = id
+	if i.idKind == idInt {
+		rowid, ok := parseRowID(id)
+		if !ok {
+			return doc, ErrDocumentNotFound
+		}
+		q = fmt.Sprintf("SELECT %s FROM %s WHERE rowid = ?", i.selectCols, i.table)
+		arg = rowid
+	}
+
+	rows, err := i.db.QueryxContext(ctx, q, arg)
 	if err != nil {
 		return doc, err
 	}
 	defer rows.Close()
 
 	if rows.Next() {
-		err = rows.StructScan(&doc)
-		return doc, err
+		return i.scan(rows)
 	}
 
 	return doc, ErrDocumentNotFound
 }
 
+// GetMany returns the documents with the given ids, in the same order.
+// Ids not in the index are skipped.
+func (i *index[K]) GetMany(ids ...string) ([]K, error) {
+	return i.GetManyContext(context.Background(), ids...)
+}
+
+func (i *index[K]) GetManyContext(ctx context.Context, ids ...string) ([]K, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	// The ids are bound as a single JSON array to stay clear of SQLite's
+	// bound parameters limit.
+	list, err := json.Marshal(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	q := fmt.Sprintf(`SELECT %s, %s FROM %s WHERE rowid IN
+		(SELECT rid FROM %s WHERE id IN (SELECT value FROM json_each(?)))`,
+		i.selectCols, quoteIdent(i.idColumn), i.table, i.idsTable)
+	rows, err := i.db.QueryxContext(ctx, q, string(list))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	found := make(map[string]K, len(ids))
+	for rows.Next() {
+		var id string
+		doc, err := i.scan(rows, &id)
+		if err != nil {
+			return nil, fmt.Errorf("struct scan failed: %w", err)
+		}
+		found[id] = doc
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	docs := make([]K, 0, len(found))
+	for _, id := range ids {
+		if doc, ok := found[id]; ok {
+			docs = append(docs, doc)
+		}
+	}
+
+	return docs, nil
+}
+
+// Exists reports whether a document with the given id is in the index.
+func (i *index[K]) Exists(id string) (bool, error) {
+	return i.ExistsContext(context.Background(), id)
+}
+
+func (i *index[K]) ExistsContext(ctx context.Context, id string) (bool, error) {
+	var exists bool
+	q := fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE id = ?)", i.idsTable)
+	err := i.db.GetContext(ctx, &exists, q, id)
+	return exists, err
+}
+
+// Count returns the number of documents matching query.
+func (i *index[K]) Count(query string) (int, error) {
+	return i.CountContext(context.Background(), query)
+}
+
+func (i *index[K]) CountContext(ctx context.Context, query string) (int, error) {
+	return i.CountWithOptionsContext(ctx, query, SearchOptions{})
+}
+
+// CountWithOptions returns the number of documents SearchWithOptions
+// would return for query without a limit, honoring opts.Mode and
+// opts.Filters. The other options are ignored.
+func (i *index[K]) CountWithOptions(query string, opts SearchOptions) (int, error) {
+	return i.CountWithOptionsContext(context.Background(), query, opts)
+}
+
+func (i *index[K]) CountWithOptionsContext(ctx context.Context, query string, opts SearchOptions) (int, error) {
+	var count int
+	var match string
+	if len(opts.Filters) == 0 || strings.TrimSpace(query) != "" {
+		var err error
+		match, err = i.matchQuery(query, opts.Mode)
+		if err != nil || match == "" {
+			return 0, err
+		}
+	}
+	where, args, err := i.matchWhere(match, opts.Filters)
+	if err != nil {
+		return 0, err
+	}
+	q := fmt.Sprintf("SELECT count(*) FROM %s WHERE %s", i.table, strings.Join(where, " AND "))
+	if err := i.db.GetContext(ctx, &count, q, args...); err != nil {
+		return 0, i.queryError(err, match)
+	}
+	return count, nil
+}
+
 func (i *index[K]) Delete(id string) error {
-	_, err := i.db.Exec("DELETE FROM fulltext_search WHERE id = ?", id)
+	return i.DeleteContext(context.Background(), id)
+}
+
+func (i *index[K]) DeleteContext(ctx context.Context, id string) error {
+	tx, err := i.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	q := fmt.Sprintf("DELETE FROM %s WHERE rowid = (%s)", i.table, i.selectRowID())
+	idQuery := fmt.Sprintf("DELETE FROM %s WHERE id = ?", i.idsTable)
+	var arg any = id
+	if i.idKind == idInt {
+		rowid, ok := parseRowID(id)
+		if !ok {
+			return nil
+		}
+		q = fmt.Sprintf("DELETE FROM %s WHERE rowid = ?", i.table)
+		idQuery = fmt.Sprintf("DELETE FROM %s WHERE rid = ?", i.idsTable)
+		arg = rowid
+	}
+
+	if _, err := tx.ExecContext(ctx, q, arg); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, idQuery, arg); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Insert inserts docs in a single transaction. If any document
+// fails to insert, none of them are and the returned *DocumentError
+// describes the failure. Inserting a document with an id already in the
+// index fails with ErrDuplicateID.
+//
+// Documents without an id get a generated one. When the index holds
+// pointers to structs, the assigned id is written to the Id field of the
+// document once the transaction commits.
+func (i *index[K]) Insert(docs ...K) error {
+	return i.InsertContext(context.Background(), docs...)
+}
+
+func (i *index[K]) InsertContext(ctx context.Context, docs ...K) error {
+	_, err := i.InsertReturningIDsContext(ctx, docs...)
 	return err
 }
 
-func (i *index[K]) Insert(docs ...K) (err error) {
-	vals := make([]any, len(i.fields))
+// InsertReturningIDs inserts docs like Insert, returning the id of every
+// document, including the generated ones. Ids are also written back to
+// the Id field of pointer documents that didn't have one.
+func (i *index[K]) InsertReturningIDs(docs ...K) ([]string, error) {
+	return i.InsertReturningIDsContext(context.Background(), docs...)
+}
+
+func (i *index[K]) InsertReturningIDsContext(ctx context.Context, docs ...K) ([]string, error) {
+	tx, err := i.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	ids, failures, err := i.insertDocs(ctx, tx, docs, true)
+	if err != nil {
+		return nil, err
+	}
+	if len(failures) > 0 {
+		return nil, failures[0]
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	for n, doc := range docs {
+		i.writeID(doc, ids[n])
+	}
+	return ids, nil
+}
+
+// Update replaces the document with the same id as doc. It fails with
+// ErrDocumentNotFound if there is no such document.
+func (i *index[K]) Update(doc K) error {
+	return i.UpdateContext(context.Background(), doc)
+}
+
+func (i *index[K]) UpdateContext(ctx context.Context, doc K) error {
+	tx, err := i.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
-	for _, doc := range docs {
-		v := reflect.ValueOf(doc)
-		if v.Kind() == reflect.Ptr {
-			v = v.Elem()
+	r, err := i.values(doc, false)
+	if err != nil {
+		return err
+	}
+	rowid, err := i.rowID(ctx, tx, r.id)
+	if err != nil {
+		return err
+	}
+	if err := i.update(ctx, tx, rowid, r.vals); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Upsert inserts docs, replacing the documents with the same id, in a
+// single transaction.
+func (i *index[K]) Upsert(docs ...K) error {
+	return i.UpsertContext(context.Background(), docs...)
+}
+
+func (i *index[K]) UpsertContext(ctx context.Context, docs ...K) error {
+	tx, err := i.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	ids := make([]string, len(docs))
+	for n, doc := range docs {
+		r, err := i.values(doc, true)
+		if err == nil {
+			var rowid int64
+			rowid, err = i.rowID(ctx, tx, r.id)
+			switch {
+			case err == nil:
+				err = i.update(ctx, tx, rowid, r.vals)
+			case errors.Is(err, ErrDocumentNotFound):
+				err = i.insert(ctx, tx, &r)
+			}
+		}
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			return &DocumentError{Index: n, Id: r.id, Err: err}
+		}
+		ids[n] = r.id
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	for n, doc := range docs {
+		i.writeID(doc, ids[n])
+	}
+	return nil
+}
+
+// insertDocs inserts docs using tx, returning the ids of the documents,
+// empty for the ones that failed, and the failures. With stopOnError set
+// it returns after the first failure. A non-nil error means the whole
+// operation must be aborted.
+func (i *index[K]) insertDocs(ctx context.Context, tx *sqlx.Tx, docs []K, stopOnError bool) ([]string, []*DocumentError, error) {
+	ids := make([]string, len(docs))
+	var failures []*DocumentError
+	for n, doc := range docs {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
 		}
-		t := v.Type()
 
-		for i := range t.NumField() {
-			field := t.Field(i)
-			value := v.Field(i).Interface()
-			if field.Name == "Id" && value == "" {
-				value = uuid.New().String()
+		r, err := i.values(doc, true)
+		if err == nil {
+			err = i.insert(ctx, tx, &r)
+		}
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, nil, ctxErr
+			}
+			failures = append(failures, &DocumentError{Index: n, Id: r.id, Err: err})
+			if stopOnError {
+				break
 			}
-			vals[i] = value
+			continue
 		}
+		ids[n] = r.id
+	}
+
+	return ids, failures, nil
+}
 
-		_, err = i.insertStmt.Exec(vals...)
+// insert adds a new document. The id is registered first, so duplicates
+// are detected before touching the full-text index. Allocated ids are set
+// on r.
+func (i *index[K]) insert(ctx context.Context, tx *sqlx.Tx, r *row) error {
+	var res sql.Result
+	var err error
+	switch {
+	case i.idKind == idInt && r.rowid == 0:
+		res, err = tx.ExecContext(ctx, fmt.Sprintf(allocIDQuery, i.idsTable))
+	case i.idKind == idInt:
+		res, err = tx.StmtContext(ctx, i.idStmt).ExecContext(ctx, r.rowid, r.id)
+	default:
+		res, err = tx.StmtContext(ctx, i.idStmt).ExecContext(ctx, nil, r.id)
+	}
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrDuplicateID
 	}
 
+	rowid, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	i.allocated(r, rowid)
+
+	args := append([]any{rowid}, r.vals...)
+	if _, err := tx.StmtContext(ctx, i.insertStmt).ExecContext(ctx, args...); err != nil {
+		// Release the id, the transaction may still be committed when
+		// other documents succeed.
+		q := fmt.Sprintf("DELETE FROM %s WHERE rid = ?", i.idsTable)
+		if _, derr := tx.ExecContext(ctx, q, rowid); derr != nil {
+			return errors.Join(err, derr)
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (i *index[K]) update(ctx context.Context, tx *sqlx.Tx, rowid int64, vals []any) error {
+	set := make([]string, len(i.fields))
+	for n, name := range i.fields {
+		set[n] = quoteIdent(name) + " = ?"
+	}
+	q := fmt.Sprintf("UPDATE %s SET %s WHERE rowid = ?", i.table, strings.Join(set, ", "))
+	_, err := tx.ExecContext(ctx, q, append(vals, rowid)...)
 	return err
 }
 
-func (i *index[K]) Search(query string) ([]K, error) {
-	rows, err := i.db.Queryx("SELECT * FROM fulltext_search WHERE fulltext_search MATCH ?", query)
-	if err != nil {
-		return nil, err
+func (i *index[K]) selectRowID() string {
+	return fmt.Sprintf(selectRowIDQuery, i.idsTable)
+}
+
+// rowID returns the rowid of the document with the given id.
+func (i *index[K]) rowID(ctx context.Context, tx *sqlx.Tx, id any) (int64, error) {
+	var rowid int64
+	err := tx.GetContext(ctx, &rowid, i.selectRowID(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrDocumentNotFound
 	}
-	defer rows.Close()
+	return rowid, err
+}
+
+// values returns the row to write for doc. When genID is set, an id is
+// generated if the document doesn't have one.
+func (i *index[K]) values(doc K, genID bool) (row, error) {
+	r := row{vals: make([]any, len(i.columns))}
+	v := reflect.ValueOf(doc)
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return r, fmt.Errorf("nil document")
+		}
+		v = v.Elem()
+	}
+	for n, c := range i.columns {
+		f := fieldByIndex(v, c.index, false)
+		if c.id {
+			if err := i.readID(&r, doc, f, genID); err != nil {
+				return r, err
+			}
+			continue
+		}
+		val, err := encodeValue(f)
+		if err != nil {
+			return r, fmt.Errorf("field %s: %w", c.name, err)
+		}
+		r.vals[n] = val
+	}
+
+	return r, nil
+}
+
+func (i *index[K]) idIndex() int {
+	return i.columnIndex(i.idColumn)
+}
 
+func (i *index[K]) Search(query string) ([]K, error) {
+	return i.SearchContext(context.Background(), query)
+}
+
+func (i *index[K]) SearchContext(ctx context.Context, query string) ([]K, error) {
 	var results []K
-	for rows.Next() {
-		var result K
-		if err := rows.StructScan(&result); err != nil {
-			return nil, fmt.Errorf("struct scan failed: %w", err)
+	for doc, err := range i.SearchIterContext(ctx, query) {
+		if err != nil {
+			return nil, err
 		}
-		results = append(results, result)
+		results = append(results, doc)
 	}
 
 	return results, nil
 }
+
+// scan reads the current row, selected with i.selectCols, into a new
+// document. Additional selected values are scanned into extra.
+func (i *index[K]) scan(rows *sqlx.Rows, extra ...any) (K, error) {
+	var doc K
+	v := reflect.ValueOf(&doc).Elem()
+	if v.Kind() == reflect.Pointer {
+		v.Set(reflect.New(v.Type().Elem()))
+		v = v.Elem()
+	}
+
+	dest := make([]any, len(i.columns), len(i.columns)+len(extra))
+	var finish []func() error
+	for n, c := range i.columns {
+		target, fn := scanTarget(fieldByIndex(v, c.index, true))
+		dest[n] = target
+		if fn != nil {
+			finish = append(finish, fn)
+		}
+	}
+	dest = append(dest, extra...)
+
+	if err := rows.Scan(dest...); err != nil {
+		return doc, err
+	}
+	for _, fn := range finish {
+		if err := fn(); err != nil {
+			return doc, err
+		}
+	}
+
+	return doc, nil
+}
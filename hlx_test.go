@@ -1,9 +1,12 @@
 package hlx
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
+	"strings"
 	"testing"
+	"unicode"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
@@ -381,3 +384,304 @@ func TestGet(t *testing.T) {
 		assert.Equal(t, u, doc.Id)
 	})
 }
+
+func TestStructTags(t *testing.T) {
+	type doc struct {
+		Id      string
+		Title   string `hlx:"headline,weight=10"`
+		Content string `hlx:",weight=0.5"`
+		Path    string `hlx:"-"`
+		Hash    string `hlx:",unindexed"`
+	}
+
+	idx, err := NewIndex[doc](":memory:")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"id", "headline", "content", "hash"}, idx.Fields())
+
+	err = idx.Insert(doc{
+		Id:      "1",
+		Title:   "Tagged document",
+		Content: "searchable body",
+		Path:    "/tmp/ignored",
+		Hash:    "deadbeef",
+	})
+	assert.NoError(t, err)
+
+	t.Run("renamed column", func(t *testing.T) {
+		results, err := idx.Search("headline:tagged")
+		assert.NoError(t, err)
+		assert.Len(t, results, 1)
+		assert.Equal(t, "Tagged document", results[0].Title)
+	})
+
+	t.Run("unindexed column is stored but not searchable", func(t *testing.T) {
+		results, err := idx.Search("deadbeef")
+		assert.NoError(t, err)
+		assert.Len(t, results, 0)
+
+		result, err := idx.Get("1")
+		assert.NoError(t, err)
+		assert.Equal(t, "deadbeef", result.Hash)
+	})
+
+	t.Run("skipped field is not stored", func(t *testing.T) {
+		result, err := idx.Get("1")
+		assert.NoError(t, err)
+		assert.Equal(t, "", result.Path)
+		assert.Equal(t, "searchable body", result.Content)
+	})
+
+	t.Run("invalid tags", func(t *testing.T) {
+		type skippedID struct {
+			Id string `hlx:"-"`
+		}
+		_, err := NewIndex[skippedID](":memory:")
+		assert.Error(t, err)
+
+		type badWeight struct {
+			Id    string
+			Title string `hlx:",weight=heavy"`
+		}
+		_, err = NewIndex[badWeight](":memory:")
+		assert.Error(t, err)
+
+		type badOption struct {
+			Id    string
+			Title string `hlx:",stored"`
+		}
+		_, err = NewIndex[badOption](":memory:")
+		assert.Error(t, err)
+
+		type duplicated struct {
+			Id      string
+			Title   string `hlx:"content"`
+			Content string
+		}
+		_, err = NewIndex[duplicated](":memory:")
+		assert.Error(t, err)
+	})
+}
+
+func TestDBTags(t *testing.T) {
+	type doc struct {
+		Id      string `db:"doc_id"`
+		Title   string `db:"headline"`
+		Content string `db:"body" hlx:",weight=2"`
+		Summary string `db:"abstract" hlx:"summary"`
+		Path    string `db:"-"`
+		Hash    string `db:"-" hlx:",unindexed"`
+	}
+
+	db := openTestDB(t)
+	idx, err := NewIndex[doc]("", WithDB(db))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"doc_id", "headline", "body", "summary", "hash"}, idx.Fields())
+
+	d := doc{Id: "1", Title: "tagged", Content: "mapped body", Summary: "short", Path: "/ignored", Hash: "abc"}
+	assert.NoError(t, idx.Insert(d))
+
+	got, err := idx.Get("1")
+	assert.NoError(t, err)
+	d.Path = ""
+	assert.Equal(t, d, got)
+
+	count, err := idx.Count("body:mapped")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	type row struct {
+		Id      string `db:"doc_id"`
+		Title   string `db:"headline"`
+		Content string `db:"body"`
+	}
+	var rows []row
+	assert.NoError(t, db.Select(&rows, "SELECT doc_id, headline, body FROM fulltext_search"))
+	assert.Equal(t, []row{{Id: "1", Title: "tagged", Content: "mapped body"}}, rows)
+}
+
+func TestWithNameMapper(t *testing.T) {
+	type doc struct {
+		Id          string
+		ReleaseDate string
+		ShortTitle  string `db:"title"`
+	}
+
+	snake := func(name string) string {
+		var b strings.Builder
+		for n, r := range name {
+			if unicode.IsUpper(r) && n > 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+		}
+		return b.String()
+	}
+
+	idx, err := NewIndex[doc](":memory:", WithNameMapper(snake))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"id", "release_date", "title"}, idx.Fields())
+
+	assert.NoError(t, idx.Insert(doc{Id: "1", ReleaseDate: "2024", ShortTitle: "mapped"}))
+	got, err := idx.Get("1")
+	assert.NoError(t, err)
+	assert.Equal(t, "2024", got.ReleaseDate)
+}
+
+func TestContext(t *testing.T) {
+	idx, err := NewIndex[TestDoc](":memory:")
+	assert.NoError(t, err)
+	assert.NoError(t, idx.InsertContext(context.Background(), TestDoc{Id: "1", Title: "context"}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = idx.InsertContext(ctx, TestDoc{Id: "2", Title: "canceled"})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = idx.GetContext(ctx, "1")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = idx.SearchContext(ctx, "context")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = idx.SearchRankedContext(ctx, "context")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = idx.SearchWithOptionsContext(ctx, "context", SearchOptions{Limit: 1})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	err = idx.DeleteContext(ctx, "1")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	doc, err := idx.Get("1")
+	assert.NoError(t, err)
+	assert.Equal(t, "context", doc.Title)
+
+	_, err = idx.Get("2")
+	assert.ErrorIs(t, err, ErrDocumentNotFound)
+}
+
+func TestGetMany(t *testing.T) {
+	idx, err := NewIndex[TestDoc](":memory:")
+	assert.NoError(t, err)
+
+	var ids []string
+	for n := range 600 {
+		id := fmt.Sprintf("doc-%d", n)
+		ids = append(ids, id)
+		assert.NoError(t, idx.Insert(TestDoc{Id: id, Title: fmt.Sprintf("title %d", n)}))
+	}
+
+	docs, err := idx.GetMany("doc-5", "missing", "doc-1", "doc-3")
+	assert.NoError(t, err)
+	assert.Len(t, docs, 3)
+	assert.Equal(t, "doc-5", docs[0].Id)
+	assert.Equal(t, "title 5", docs[0].Title)
+	assert.Equal(t, "doc-1", docs[1].Id)
+	assert.Equal(t, "doc-3", docs[2].Id)
+
+	docs, err = idx.GetMany(ids...)
+	assert.NoError(t, err)
+	assert.Len(t, docs, 600)
+
+	docs, err = idx.GetMany()
+	assert.NoError(t, err)
+	assert.Len(t, docs, 0)
+}
+
+func TestExists(t *testing.T) {
+	idx, err := NewIndex[TestDoc](":memory:")
+	assert.NoError(t, err)
+	assert.NoError(t, idx.Insert(TestDoc{Id: "1"}))
+
+	exists, err := idx.Exists("1")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	exists, err = idx.Exists("2")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	assert.NoError(t, idx.Delete("1"))
+	exists, err = idx.Exists("1")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestCount(t *testing.T) {
+	idx, err := NewIndex[TestDoc](":memory:")
+	assert.NoError(t, err)
+
+	for n := range 15 {
+		title := "odd"
+		if n%2 == 0 {
+			title = "even"
+		}
+		assert.NoError(t, idx.Insert(TestDoc{Title: title}))
+	}
+
+	count, err := idx.Count("even")
+	assert.NoError(t, err)
+	assert.Equal(t, 8, count)
+
+	count, err = idx.Count("even OR odd")
+	assert.NoError(t, err)
+	assert.Equal(t, 15, count)
+
+	count, err = idx.Count("nothing")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestWithTableName(t *testing.T) {
+	db, err := sqlx.Open("sqlite3", filepath.Join(t.TempDir(), "shared.db"))
+	assert.NoError(t, err)
+	defer db.Close()
+
+	type note struct {
+		Id   string
+		Body string
+	}
+	type email struct {
+		Id      string
+		Subject string
+		From    string
+	}
+
+	notes, err := NewIndex[note]("", WithDB(db), WithTableName("notes"))
+	assert.NoError(t, err)
+	emails, err := NewIndex[email]("", WithDB(db), WithTableName("inbox emails"))
+	assert.NoError(t, err)
+
+	assert.NoError(t, notes.Insert(note{Id: "1", Body: "shared database"}))
+	assert.NoError(t, emails.Insert(email{Id: "1", Subject: "shared inbox", From: "bob"}))
+
+	n, err := notes.Get("1")
+	assert.NoError(t, err)
+	assert.Equal(t, "shared database", n.Body)
+
+	e, err := emails.Get("1")
+	assert.NoError(t, err)
+	assert.Equal(t, "shared inbox", e.Subject)
+
+	results, err := emails.SearchWithOptions("shared", SearchOptions{Highlight: &HighlightOptions{Fields: []string{"subject"}}})
+	assert.NoError(t, err)
+	assert.Len(t, results.Results, 1)
+	assert.Equal(t, "<b>shared</b> inbox", results.Results[0].Highlights["subject"])
+
+	count, err := notes.Count("shared")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	assert.NoError(t, notes.Delete("1"))
+	exists, err := emails.Exists("1")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	var tables int
+	assert.NoError(t, db.Get(&tables, "SELECT count(*) FROM sqlite_master WHERE name = 'fulltext_search'"))
+	assert.Equal(t, 0, tables)
+
+	_, err = NewIndex[note]("", WithDB(db), WithTableName(""))
+	assert.Error(t, err)
+}
@@ -0,0 +1,170 @@
+package hlx
+
+import (
+	"database/sql"
+	"encoding"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+// idKind is how the Id field of a document type is stored.
+type idKind int
+
+const (
+	// idString ids are stored as is, and generated when empty.
+	idString idKind = iota
+	// idInt ids are used as the rowid of the document, and allocated
+	// when zero.
+	idInt
+	// idText ids are stored as their string representation. Only
+	// uuid.UUID ids are generated when zero.
+	idText
+)
+
+var (
+	stringerType        = reflect.TypeFor[fmt.Stringer]()
+	scannerType         = reflect.TypeFor[sql.Scanner]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+	uuidType            = reflect.TypeFor[uuid.UUID]()
+)
+
+// idKindOf returns how ids of type t are stored.
+func idKindOf(t reflect.Type) (idKind, error) {
+	// String and integer types are stored as is, even when they implement
+	// fmt.Stringer.
+	switch {
+	case t.Kind() == reflect.String:
+		return idString, nil
+	case isIntKind(t.Kind()):
+		return idInt, nil
+	case t.Implements(stringerType) || reflect.PointerTo(t).Implements(stringerType):
+		pt := reflect.PointerTo(t)
+		if !pt.Implements(scannerType) && !pt.Implements(textUnmarshalerType) {
+			return 0, fmt.Errorf("Id type %s must implement sql.Scanner or encoding.TextUnmarshaler", t)
+		}
+		return idText, nil
+	}
+
+	return 0, fmt.Errorf("unsupported Id type %s", t)
+}
+
+func isIntKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// row is a document ready to be written to the index.
+type row struct {
+	// vals are the column values, in column order.
+	vals []any
+	// id is the document id, empty when it's allocated on insert.
+	id string
+	// rowid of documents with integer ids, zero when allocated on insert.
+	rowid int64
+}
+
+// readID sets the id of r from the Id field f. When genID is set, an id
+// is generated for documents that don't have one.
+func (i *index[K]) readID(r *row, doc K, f reflect.Value, genID bool) error {
+	switch i.idKind {
+	case idString:
+		r.id = f.String()
+		if r.id == "" && genID {
+			r.id = i.idGen(doc)
+		}
+		r.vals[i.idIndex()] = r.id
+	case idInt:
+		if f.CanUint() {
+			if f.Uint() > math.MaxInt64 {
+				return fmt.Errorf("id %d out of range", f.Uint())
+			}
+			r.rowid = int64(f.Uint())
+		} else {
+			r.rowid = f.Int()
+		}
+		if r.rowid != 0 {
+			r.id = strconv.FormatInt(r.rowid, 10)
+		}
+		r.vals[i.idIndex()] = r.rowid
+	case idText:
+		if f.IsZero() {
+			if !genID {
+				break
+			}
+			if i.idGen == nil {
+				return ErrMissingID
+			}
+			r.id = i.idGen(doc)
+			if err := parseID(reflect.New(f.Type()).Interface(), r.id); err != nil {
+				return fmt.Errorf("invalid generated id: %w", err)
+			}
+		} else {
+			r.id = fmt.Sprint(f.Interface())
+		}
+		r.vals[i.idIndex()] = r.id
+	}
+
+	return nil
+}
+
+// parseID scans id into dst, a pointer to an Id field.
+func parseID(dst any, id string) error {
+	if s, ok := dst.(sql.Scanner); ok {
+		return s.Scan(id)
+	}
+	return dst.(encoding.TextUnmarshaler).UnmarshalText([]byte(id))
+}
+
+// allocated sets the id of a row inserted with the given rowid.
+func (i *index[K]) allocated(r *row, rowid int64) {
+	if i.idKind == idInt {
+		r.rowid = rowid
+		r.id = strconv.FormatInt(rowid, 10)
+		r.vals[i.idIndex()] = rowid
+	}
+}
+
+// writeID sets the Id field of doc to the id assigned when inserting it.
+// Only pointer documents can be written to, and ids already set are left
+// untouched.
+func (i *index[K]) writeID(doc K, id string) {
+	v := reflect.ValueOf(doc)
+	if v.Kind() != reflect.Pointer || v.IsNil() || id == "" {
+		return
+	}
+	f := fieldByIndex(v.Elem(), i.columns[i.idIndex()].index, true)
+	if !f.IsZero() {
+		return
+	}
+
+	switch i.idKind {
+	case idString:
+		f.SetString(id)
+	case idInt:
+		rowid, _ := parseRowID(id)
+		if f.CanUint() {
+			f.SetUint(uint64(rowid))
+		} else {
+			f.SetInt(rowid)
+		}
+	case idText:
+		v := reflect.New(f.Type())
+		if parseID(v.Interface(), id) == nil {
+			f.Set(v.Elem())
+		}
+	}
+}
+
+// parseRowID returns the rowid of a document with an integer id.
+func parseRowID(id string) (int64, bool) {
+	rowid, err := strconv.ParseInt(id, 10, 64)
+	return rowid, err == nil
+}
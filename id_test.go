@@ -0,0 +1,275 @@
+package hlx
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPointerDocuments(t *testing.T) {
+	idx, err := NewIndex[*TestDoc](":memory:")
+	assert.NoError(t, err)
+
+	assert.NoError(t, idx.Insert(&TestDoc{Id: "1", Title: "pointer"}, &TestDoc{Id: "2", Title: "document"}))
+
+	doc, err := idx.Get("1")
+	assert.NoError(t, err)
+	assert.Equal(t, &TestDoc{Id: "1", Title: "pointer"}, doc)
+
+	results, err := idx.Search("document")
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, "2", results[0].Id)
+
+	docs, err := idx.GetMany("2", "1")
+	assert.NoError(t, err)
+	assert.Len(t, docs, 2)
+	assert.NotSame(t, docs[0], docs[1])
+
+	err = idx.Insert(nil)
+	assert.Error(t, err)
+
+	_, err = NewIndex[string](":memory:")
+	assert.Error(t, err)
+}
+
+func TestIntegerIDs(t *testing.T) {
+	type doc struct {
+		Id    int64
+		Title string
+	}
+
+	idx, err := NewIndex[doc](":memory:")
+	assert.NoError(t, err)
+
+	assert.NoError(t, idx.Insert(doc{Id: 42, Title: "explicit"}, doc{Title: "allocated"}))
+
+	d, err := idx.Get("42")
+	assert.NoError(t, err)
+	assert.Equal(t, doc{Id: 42, Title: "explicit"}, d)
+
+	d, err = idx.Get("43")
+	assert.NoError(t, err)
+	assert.Equal(t, doc{Id: 43, Title: "allocated"}, d)
+
+	var rowid int64
+	assert.NoError(t, idx.(*index[doc]).db.Get(&rowid, "SELECT rowid FROM fulltext_search WHERE title = 'explicit'"))
+	assert.Equal(t, int64(42), rowid)
+
+	err = idx.Insert(doc{Id: 42})
+	assert.ErrorIs(t, err, ErrDuplicateID)
+
+	_, err = idx.Get("not a number")
+	assert.ErrorIs(t, err, ErrDocumentNotFound)
+
+	exists, err := idx.Exists("43")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	assert.NoError(t, idx.Update(doc{Id: 43, Title: "updated"}))
+	d, err = idx.Get("43")
+	assert.NoError(t, err)
+	assert.Equal(t, "updated", d.Title)
+
+	assert.NoError(t, idx.Delete("42"))
+	_, err = idx.Get("42")
+	assert.ErrorIs(t, err, ErrDocumentNotFound)
+	assert.NoError(t, idx.Insert(doc{Id: 42, Title: "reinserted"}))
+
+	t.Run("unsigned", func(t *testing.T) {
+		type doc struct {
+			Id    uint
+			Title string
+		}
+		idx, err := NewIndex[doc](":memory:")
+		assert.NoError(t, err)
+		assert.NoError(t, idx.Insert(doc{Title: "first"}, doc{Title: "second"}))
+
+		docs, err := idx.GetMany("1", "2")
+		assert.NoError(t, err)
+		assert.Equal(t, []doc{{Id: 1, Title: "first"}, {Id: 2, Title: "second"}}, docs)
+	})
+}
+
+func TestUUIDIDs(t *testing.T) {
+	type doc struct {
+		Id    uuid.UUID
+		Title string
+	}
+
+	idx, err := NewIndex[doc](":memory:")
+	assert.NoError(t, err)
+
+	id := uuid.New()
+	assert.NoError(t, idx.Insert(doc{Id: id, Title: "explicit"}, doc{Title: "generated"}))
+
+	d, err := idx.Get(id.String())
+	assert.NoError(t, err)
+	assert.Equal(t, doc{Id: id, Title: "explicit"}, d)
+
+	results, err := idx.Search("generated")
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.NotEqual(t, uuid.Nil, results[0].Id)
+
+	err = idx.Insert(doc{Id: id})
+	assert.ErrorIs(t, err, ErrDuplicateID)
+}
+
+// sku is an id type only implementing encoding.TextUnmarshaler.
+type sku struct {
+	vendor string
+	code   int
+}
+
+func (s sku) String() string {
+	return fmt.Sprintf("%s-%d", s.vendor, s.code)
+}
+
+func (s *sku) UnmarshalText(b []byte) error {
+	vendor, code, ok := strings.Cut(string(b), "-")
+	if !ok {
+		return fmt.Errorf("invalid sku %q", b)
+	}
+	s.vendor = vendor
+	_, err := fmt.Sscan(code, &s.code)
+	return err
+}
+
+func TestStringerIDs(t *testing.T) {
+	type doc struct {
+		Id    sku
+		Title string
+	}
+
+	idx, err := NewIndex[doc](":memory:")
+	assert.NoError(t, err)
+
+	assert.NoError(t, idx.Insert(doc{Id: sku{"acme", 7}, Title: "anvil"}))
+	d, err := idx.Get("acme-7")
+	assert.NoError(t, err)
+	assert.Equal(t, doc{Id: sku{"acme", 7}, Title: "anvil"}, d)
+
+	err = idx.Insert(doc{Title: "no id"})
+	assert.ErrorIs(t, err, ErrMissingID)
+
+	type unsupported struct {
+		Id float64
+	}
+	_, err = NewIndex[unsupported](":memory:")
+	assert.Error(t, err)
+
+	type notScannable struct {
+		Id fmt.Stringer
+	}
+	_, err = NewIndex[notScannable](":memory:")
+	assert.Error(t, err)
+}
+
+// docID and numID are string and integer id types with a String method,
+// stored like plain strings and integers.
+type docID string
+
+func (d docID) String() string { return "doc:" + string(d) }
+
+type numID int64
+
+func (n numID) String() string { return fmt.Sprintf("#%d", int64(n)) }
+
+func TestNamedIDs(t *testing.T) {
+	type strDoc struct {
+		Id    docID
+		Title string
+	}
+	idx, err := NewIndex[strDoc](":memory:")
+	assert.NoError(t, err)
+	assert.NoError(t, idx.Insert(strDoc{Id: "a", Title: "named string"}))
+	d, err := idx.Get("a")
+	assert.NoError(t, err)
+	assert.Equal(t, strDoc{Id: "a", Title: "named string"}, d)
+
+	type numDoc struct {
+		Id    numID
+		Title string
+	}
+	nidx, err := NewIndex[numDoc](":memory:")
+	assert.NoError(t, err)
+	ids, err := nidx.InsertReturningIDs(numDoc{Id: 10, Title: "named int"}, numDoc{Title: "allocated"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10", "11"}, ids)
+	n, err := nidx.Get("10")
+	assert.NoError(t, err)
+	assert.Equal(t, numDoc{Id: 10, Title: "named int"}, n)
+}
+
+func TestIDWriteBack(t *testing.T) {
+	t.Run("string", func(t *testing.T) {
+		idx, err := NewIndex[*TestDoc](":memory:")
+		assert.NoError(t, err)
+
+		doc := &TestDoc{Title: "generated"}
+		given := &TestDoc{Id: "given", Title: "given"}
+		assert.NoError(t, idx.Insert(doc, given))
+		assert.NotEmpty(t, doc.Id)
+		assert.Equal(t, "given", given.Id)
+
+		got, err := idx.Get(doc.Id)
+		assert.NoError(t, err)
+		assert.Equal(t, "generated", got.Title)
+		assert.NoError(t, idx.Delete(doc.Id))
+	})
+
+	t.Run("int", func(t *testing.T) {
+		type doc struct {
+			Id    uint32
+			Title string
+		}
+		idx, err := NewIndex[*doc](":memory:")
+		assert.NoError(t, err)
+
+		a, b := &doc{Title: "a"}, &doc{Title: "b"}
+		assert.NoError(t, idx.Upsert(a, b))
+		assert.Equal(t, uint32(1), a.Id)
+		assert.Equal(t, uint32(2), b.Id)
+	})
+
+	t.Run("UUID", func(t *testing.T) {
+		type doc struct {
+			Id    uuid.UUID
+			Title string
+		}
+		idx, err := NewIndex[*doc](":memory:")
+		assert.NoError(t, err)
+
+		d := &doc{Title: "uuid"}
+		ids, err := idx.InsertReturningIDs(d)
+		assert.NoError(t, err)
+		assert.Equal(t, ids[0], d.Id.String())
+	})
+
+	t.Run("bulk", func(t *testing.T) {
+		idx, err := NewIndex[*TestDoc](":memory:")
+		assert.NoError(t, err)
+		assert.NoError(t, idx.Insert(&TestDoc{Id: "taken"}))
+
+		ok, dup := &TestDoc{Title: "ok"}, &TestDoc{Id: "taken"}
+		assert.Error(t, idx.BulkInsert(AllOrNothing, ok, dup))
+		assert.Empty(t, ok.Id)
+
+		assert.Error(t, idx.BulkInsert(BestEffort, ok, dup))
+		assert.NotEmpty(t, ok.Id)
+	})
+
+	t.Run("failed insert", func(t *testing.T) {
+		idx, err := NewIndex[*TestDoc](":memory:")
+		assert.NoError(t, err)
+		assert.NoError(t, idx.Insert(&TestDoc{Id: "taken"}))
+
+		doc := &TestDoc{Title: "rolled back"}
+		assert.ErrorIs(t, idx.Insert(doc, &TestDoc{Id: "taken"}), ErrDuplicateID)
+		assert.Empty(t, doc.Id)
+	})
+}
@@ -0,0 +1,132 @@
+package hlx
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WithIDGenerator sets the function generating the ids of documents
+// inserted without one. It must be instantiated with the document type of
+// the index, e.g. WithIDGenerator(hlx.ULID[Document]). Indexes with
+// integer ids don't support generators.
+func WithIDGenerator[K any](gen func(K) string) Option {
+	return func(o *Options) {
+		o.idGenerator = gen
+	}
+}
+
+// UUIDv4 generates random UUIDs. It's the default generator.
+func UUIDv4[K any](K) string {
+	return uuid.New().String()
+}
+
+// UUIDv7 generates time ordered UUIDs, which improves the locality of
+// recently inserted documents.
+func UUIDv7[K any](K) string {
+	return uuid.Must(uuid.NewV7()).String()
+}
+
+// ULID generates Universally Unique Lexicographically Sortable
+// Identifiers, 26 characters strings that sort by creation time.
+func ULID[K any](K) string {
+	return newULID(time.Now())
+}
+
+// ContentHash generates the hex encoded SHA-256 hash of the indexed fields
+// of the document, excluding the id and unindexed fields. Documents with
+// the same content get the same id, so inserting them again fails with
+// ErrDuplicateID, and Upsert is idempotent.
+//
+// Fields are identified by their Go names, so the hash doesn't depend on
+// column names and is the same for every index of the document type,
+// whatever its name mapper. It's empty for types with invalid struct tags,
+// which NewIndex rejects.
+func ContentHash[K any](doc K) string {
+	v := reflect.ValueOf(doc)
+	if v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+
+	fields, err := hashFields(v.Type())
+	if err != nil {
+		return ""
+	}
+	h := sha256.New()
+	for _, f := range fields {
+		val, _ := encodeValue(fieldByIndex(v, f.index, false))
+		fmt.Fprintf(h, "%s\x00%v\x00", f.name, val)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// hashField is a field hashed by ContentHash, named by its Go field path.
+type hashField struct {
+	name  string
+	index []int
+}
+
+type hashFieldsEntry struct {
+	fields []hashField
+	err    error
+}
+
+var hashFieldsCache sync.Map
+
+// hashFields returns the fields of t hashed by ContentHash: the fields
+// stored in indexed columns, other than the id. Fields hidden by a field
+// with the same column name are included, since which fields are hidden
+// depends on the name mapper of the index.
+func hashFields(t reflect.Type) ([]hashField, error) {
+	if e, ok := hashFieldsCache.Load(t); ok {
+		return e.(hashFieldsEntry).fields, e.(hashFieldsEntry).err
+	}
+
+	var all columns
+	var fields []hashField
+	err := appendColumns(&all, t, nil, strings.ToLower)
+	if err == nil {
+		for _, c := range all {
+			if c.id || c.unindexed {
+				continue
+			}
+			names := make([]string, len(c.index))
+			for n := range c.index {
+				names[n] = t.FieldByIndex(c.index[:n+1]).Name
+			}
+			fields = append(fields, hashField{name: strings.Join(names, "."), index: c.index})
+		}
+	}
+	hashFieldsCache.Store(t, hashFieldsEntry{fields: fields, err: err})
+	return fields, err
+}
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// newULID encodes the 48 bits millisecond timestamp of t followed by 80
+// random bits in Crockford's base32.
+func newULID(t time.Time) string {
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], uint64(t.UnixMilli())<<16)
+	if _, err := rand.Read(b[6:]); err != nil {
+		panic(err)
+	}
+
+	hi := binary.BigEndian.Uint64(b[:8])
+	lo := binary.BigEndian.Uint64(b[8:])
+	var s [26]byte
+	for n := len(s) - 1; n >= 0; n-- {
+		s[n] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(s[:])
+}
@@ -0,0 +1,149 @@
+package hlx
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestULID(t *testing.T) {
+	now := time.Now()
+	id := newULID(now)
+	assert.Len(t, id, 26)
+	for _, c := range id {
+		assert.True(t, strings.ContainsRune(crockford, c), "invalid character %q", c)
+	}
+
+	assert.Equal(t, "0000000000", newULID(time.UnixMilli(0))[:10])
+	assert.Equal(t, "01ARZ3NDEK", newULID(time.UnixMilli(1469922850259))[:10])
+	assert.Less(t, newULID(now), newULID(now.Add(time.Millisecond)))
+	assert.NotEqual(t, newULID(now), newULID(now))
+}
+
+func TestIDGenerators(t *testing.T) {
+	t.Run("UUIDv7", func(t *testing.T) {
+		idx, err := NewIndex[TestDoc](":memory:", WithIDGenerator(UUIDv7[TestDoc]))
+		assert.NoError(t, err)
+
+		ids, err := idx.InsertReturningIDs(TestDoc{Title: "first"}, TestDoc{Id: "given", Title: "second"})
+		assert.NoError(t, err)
+		assert.Len(t, ids, 2)
+		u, err := uuid.Parse(ids[0])
+		assert.NoError(t, err)
+		assert.Equal(t, uuid.Version(7), u.Version())
+		assert.Equal(t, "given", ids[1])
+
+		doc, err := idx.Get(ids[0])
+		assert.NoError(t, err)
+		assert.Equal(t, "first", doc.Title)
+	})
+
+	t.Run("ULID", func(t *testing.T) {
+		idx, err := NewIndex[*TestDoc](":memory:", WithIDGenerator(ULID[*TestDoc]))
+		assert.NoError(t, err)
+
+		ids, err := idx.InsertReturningIDs(&TestDoc{Title: "ulid"})
+		assert.NoError(t, err)
+		assert.Len(t, ids[0], 26)
+	})
+
+	t.Run("ContentHash", func(t *testing.T) {
+		type doc struct {
+			Id      string
+			Title   string
+			Fetched string `hlx:",unindexed"`
+			Path    string `hlx:"-"`
+		}
+		idx, err := NewIndex[doc](":memory:", WithIDGenerator(ContentHash[doc]))
+		assert.NoError(t, err)
+
+		ids, err := idx.InsertReturningIDs(doc{Title: "same", Fetched: "monday", Path: "/a"})
+		assert.NoError(t, err)
+		assert.Len(t, ids[0], 64)
+		assert.Equal(t, ContentHash(doc{Title: "same", Id: "ignored"}), ids[0])
+		assert.NotEqual(t, ContentHash(doc{Title: "other"}), ids[0])
+
+		err = idx.Insert(doc{Title: "same", Fetched: "tuesday", Path: "/b"})
+		assert.ErrorIs(t, err, ErrDuplicateID)
+
+		assert.NoError(t, idx.Upsert(doc{Title: "same", Fetched: "tuesday"}))
+		d, err := idx.Get(ids[0])
+		assert.NoError(t, err)
+		assert.Equal(t, "tuesday", d.Fetched)
+
+		count, err := idx.Count("same")
+		assert.NoError(t, err)
+		assert.Equal(t, 1, count)
+	})
+
+	t.Run("ContentHash name mapper", func(t *testing.T) {
+		type doc struct {
+			Id     string
+			UserID string
+			Userid string
+		}
+		mapper := func(name string) string {
+			if name == "UserID" {
+				return "user_id"
+			}
+			return name
+		}
+		idx, err := NewIndex[doc](":memory:", WithNameMapper(mapper), WithIDGenerator(ContentHash[doc]))
+		assert.NoError(t, err)
+
+		ids, err := idx.InsertReturningIDs(doc{UserID: "a", Userid: "b"}, doc{UserID: "b", Userid: "a"})
+		assert.NoError(t, err)
+		assert.Equal(t, ContentHash(doc{UserID: "a", Userid: "b"}), ids[0])
+		assert.NotEqual(t, ids[0], ids[1])
+
+		type invalid struct {
+			Id    string
+			Title string `hlx:",bogus"`
+		}
+		assert.Empty(t, ContentHash(invalid{Title: "x"}))
+	})
+
+	t.Run("custom", func(t *testing.T) {
+		idx, err := NewIndex[TestDoc](":memory:", WithIDGenerator(func(d TestDoc) string {
+			return "doc-" + strings.ToLower(d.Title)
+		}))
+		assert.NoError(t, err)
+
+		ids, err := idx.InsertReturningIDs(TestDoc{Title: "Custom"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"doc-custom"}, ids)
+	})
+
+	t.Run("UUID ids", func(t *testing.T) {
+		type doc struct {
+			Id    uuid.UUID
+			Title string
+		}
+		idx, err := NewIndex[doc](":memory:", WithIDGenerator(UUIDv7[doc]))
+		assert.NoError(t, err)
+		ids, err := idx.InsertReturningIDs(doc{Title: "v7"})
+		assert.NoError(t, err)
+		d, err := idx.Get(ids[0])
+		assert.NoError(t, err)
+		assert.Equal(t, uuid.Version(7), d.Id.Version())
+
+		idx, err = NewIndex[doc](":memory:", WithIDGenerator(ULID[doc]))
+		assert.NoError(t, err)
+		err = idx.Insert(doc{Title: "ulid is not a uuid"})
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid generators", func(t *testing.T) {
+		_, err := NewIndex[TestDoc](":memory:", WithIDGenerator(ULID[*TestDoc]))
+		assert.Error(t, err)
+
+		type doc struct {
+			Id int
+		}
+		_, err = NewIndex[doc](":memory:", WithIDGenerator(ULID[doc]))
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,78 @@
+package hlx
+
+import (
+	"context"
+	"fmt"
+	"iter"
+)
+
+// SearchIter returns an iterator over the documents matching query. Rows
+// are read from the database as the iteration advances, so the results are
+// never held in memory at once. Iteration stops after yielding an error.
+func (i *index[K]) SearchIter(query string) iter.Seq2[K, error] {
+	return i.SearchIterContext(context.Background(), query)
+}
+
+func (i *index[K]) SearchIterContext(ctx context.Context, query string) iter.Seq2[K, error] {
+	match, err := i.matchQuery(query, QueryDefault)
+	if err != nil {
+		return func(yield func(K, error) bool) {
+			var zero K
+			yield(zero, err)
+		}
+	}
+	if match == "" {
+		return func(func(K, error) bool) {}
+	}
+	q := fmt.Sprintf("SELECT %s FROM %[2]s WHERE %[2]s MATCH ?", i.selectCols, i.table)
+	return func(yield func(K, error) bool) {
+		for doc, err := range i.iter(ctx, q, match) {
+			if err != nil {
+				err = i.queryError(err, match)
+			}
+			if !yield(doc, err) {
+				return
+			}
+		}
+	}
+}
+
+// All returns an iterator over every document in the index, in insertion
+// order. Iteration stops after yielding an error.
+func (i *index[K]) All() iter.Seq2[K, error] {
+	return i.AllContext(context.Background())
+}
+
+func (i *index[K]) AllContext(ctx context.Context) iter.Seq2[K, error] {
+	q := fmt.Sprintf("SELECT %s FROM %s ORDER BY rowid", i.selectCols, i.table)
+	return i.iter(ctx, q)
+}
+
+// iter runs q when iterated, yielding a document per row. The rows are
+// closed when the loop ends, even if it breaks early.
+func (i *index[K]) iter(ctx context.Context, q string, args ...any) iter.Seq2[K, error] {
+	return func(yield func(K, error) bool) {
+		var zero K
+		rows, err := i.db.QueryxContext(ctx, q, args...)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			doc, err := i.scan(rows)
+			if err != nil {
+				yield(zero, fmt.Errorf("struct scan failed: %w", err))
+				return
+			}
+			if !yield(doc, nil) {
+				return
+			}
+		}
+
+		if err := rows.Err(); err != nil {
+			yield(zero, err)
+		}
+	}
+}
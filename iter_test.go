@@ -0,0 +1,80 @@
+package hlx
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchIter(t *testing.T) {
+	idx, err := NewIndex[TestDoc](":memory:")
+	assert.NoError(t, err)
+
+	for n := range 10 {
+		title := "odd"
+		if n%2 == 0 {
+			title = "even"
+		}
+		assert.NoError(t, idx.Insert(TestDoc{Id: fmt.Sprint(n), Title: title}))
+	}
+
+	var ids []string
+	for doc, err := range idx.SearchIter("even") {
+		assert.NoError(t, err)
+		ids = append(ids, doc.Id)
+	}
+	assert.Equal(t, []string{"0", "2", "4", "6", "8"}, ids)
+
+	t.Run("break", func(t *testing.T) {
+		count := 0
+		for _, err := range idx.SearchIter("odd") {
+			assert.NoError(t, err)
+			count++
+			if count == 2 {
+				break
+			}
+		}
+		assert.Equal(t, 2, count)
+
+		// The rows of the interrupted iteration must have been released.
+		assert.NoError(t, idx.Insert(TestDoc{Id: "10", Title: "even"}))
+	})
+
+	t.Run("error", func(t *testing.T) {
+		var errs []error
+		for _, err := range idx.SearchIter(`"unterminated`) {
+			errs = append(errs, err)
+		}
+		assert.Len(t, errs, 1)
+		assert.Error(t, errs[0])
+	})
+}
+
+func TestAll(t *testing.T) {
+	idx, err := NewIndex[TestDoc](":memory:")
+	assert.NoError(t, err)
+
+	assert.NoError(t, idx.Insert(
+		TestDoc{Id: "b", Title: "first"},
+		TestDoc{Id: "a", Title: "second"},
+		TestDoc{Id: "c", Title: "third"},
+	))
+
+	var titles []string
+	for doc, err := range idx.All() {
+		assert.NoError(t, err)
+		titles = append(titles, doc.Title)
+	}
+	assert.Equal(t, []string{"first", "second", "third"}, titles)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var errs []error
+	for _, err := range idx.AllContext(ctx) {
+		errs = append(errs, err)
+	}
+	assert.Len(t, errs, 1)
+	assert.ErrorIs(t, errs[0], context.Canceled)
+}
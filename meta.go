@@ -0,0 +1,167 @@
+package hlx
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// schemaVersion is the version of the metadata stored in metaTable. It
+// changes when the way hlx lays out its tables does.
+const schemaVersion = 1
+
+// metaTable stores the schema of every index in the database, to detect
+// documents types that no longer match the table they are stored in.
+const metaTable = "hlx_schema"
+
+// schemaMeta is the schema of an index, as stored in metaTable.
+type schemaMeta struct {
+	Version   int          `json:"version"`
+	Columns   []columnMeta `json:"columns"`
+	Tokenizer string       `json:"tokenizer,omitempty"`
+	Prefixes  []int        `json:"prefixes,omitempty"`
+}
+
+type columnMeta struct {
+	Name      string `json:"name"`
+	Unindexed bool   `json:"unindexed,omitempty"`
+}
+
+func (s schema) meta() schemaMeta {
+	m := schemaMeta{
+		Version:   schemaVersion,
+		Tokenizer: s.tokenizer.String(),
+		Prefixes:  s.prefixes,
+	}
+	for _, c := range s.columns {
+		m.Columns = append(m.Columns, columnMeta{Name: c.name, Unindexed: c.unindexed})
+	}
+	return m
+}
+
+func initMeta(ctx context.Context, db *sqlx.DB) error {
+	_, err := db.ExecContext(ctx, fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s (table_name TEXT PRIMARY KEY, version INTEGER NOT NULL, schema TEXT NOT NULL)",
+		metaTable))
+	return err
+}
+
+// loadMeta returns the stored schema of the given FTS5 table, or nil if the
+// table doesn't exist. Tables created before the schema was recorded get
+// their columns from the table definition.
+func loadMeta(ctx context.Context, db sqlx.QueryerContext, table string) (*schemaMeta, error) {
+	var exists bool
+	err := sqlx.GetContext(ctx, db, &exists,
+		"SELECT count(*) > 0 FROM sqlite_master WHERE type = 'table' AND name = ?", table)
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	var raw string
+	err = sqlx.GetContext(ctx, db, &raw,
+		fmt.Sprintf("SELECT schema FROM %s WHERE table_name = ?", metaTable), table)
+	if err == nil {
+		m := &schemaMeta{}
+		if err := json.Unmarshal([]byte(raw), m); err != nil {
+			return nil, fmt.Errorf("invalid schema metadata for table %s: %w", table, err)
+		}
+		return m, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	var names []string
+	err = sqlx.SelectContext(ctx, db, &names,
+		"SELECT name FROM pragma_table_info(?) ORDER BY cid", table)
+	if err != nil {
+		return nil, err
+	}
+	m := &schemaMeta{}
+	for _, name := range names {
+		m.Columns = append(m.Columns, columnMeta{Name: name})
+	}
+
+	return m, nil
+}
+
+func saveMeta(ctx context.Context, db sqlx.ExecerContext, table string, m schemaMeta) error {
+	raw, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.ExecContext(ctx, fmt.Sprintf(
+		"INSERT INTO %s (table_name, version, schema) VALUES (?, ?, ?) "+
+			"ON CONFLICT (table_name) DO UPDATE SET version = excluded.version, schema = excluded.schema",
+		metaTable), table, m.Version, string(raw))
+	return err
+}
+
+// diff compares the stored schema with the schema of the document type,
+// returning nil if they match.
+func (m *schemaMeta) diff(s schema) *SchemaMismatchError {
+	e := &SchemaMismatchError{Table: s.table}
+
+	stored := map[string]columnMeta{}
+	for _, c := range m.Columns {
+		stored[c.Name] = c
+	}
+	current := map[string]bool{}
+	for _, c := range s.columns {
+		current[c.name] = true
+		sc, ok := stored[c.name]
+		switch {
+		case !ok:
+			e.Added = append(e.Added, c.name)
+		case m.Version > 0 && sc.Unindexed != c.unindexed:
+			e.Changed = append(e.Changed, c.name)
+		}
+	}
+	for _, c := range m.Columns {
+		if !current[c.Name] {
+			e.Removed = append(e.Removed, c.Name)
+		}
+	}
+
+	if s.tokenizer.String() != m.Tokenizer {
+		e.Options = append(e.Options, "tokenizer")
+	}
+	if !slices.Equal(s.prefixes, m.Prefixes) {
+		e.Options = append(e.Options, "prefix")
+	}
+
+	if len(e.Added)+len(e.Removed)+len(e.Changed)+len(e.Options) == 0 {
+		return nil
+	}
+	return e
+}
+
+// inherit sets the tokenizer and prefix indexes of the stored schema on s
+// when the document type doesn't set them, so an existing index keeps
+// using the ones it was created with.
+func (m *schemaMeta) inherit(s *schema) {
+	if s.tokenizer.isZero() && m.Tokenizer != "" {
+		s.tokenizer = Tokenizer{args: []string{m.Tokenizer}}
+	}
+	if len(s.prefixes) == 0 {
+		s.prefixes = m.Prefixes
+	}
+}
+
+// sortColumns orders the columns of s like the stored table, which may
+// differ from the order of the document fields.
+func (m *schemaMeta) sortColumns(s *schema) {
+	pos := map[string]int{}
+	for n, c := range m.Columns {
+		pos[c.Name] = n
+	}
+	slices.SortStableFunc(s.columns, func(a, b column) int {
+		return pos[a.name] - pos[b.name]
+	})
+}
@@ -0,0 +1,131 @@
+package hlx
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func openTestDB(t *testing.T) *sqlx.DB {
+	t.Helper()
+	db, err := sqlx.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestSchemaMismatch(t *testing.T) {
+	type v1 struct {
+		Id    string
+		Title string
+		Body  string
+	}
+
+	t.Run("added and removed columns", func(t *testing.T) {
+		db := openTestDB(t)
+		_, err := NewIndex[v1]("", WithDB(db))
+		assert.NoError(t, err)
+
+		type v2 struct {
+			Id      string
+			Title   string
+			Summary string
+			Author  string
+		}
+		_, err = NewIndex[v2]("", WithDB(db))
+		assert.ErrorIs(t, err, ErrSchemaMismatch)
+		var mismatch *SchemaMismatchError
+		assert.True(t, errors.As(err, &mismatch))
+		assert.Equal(t, "fulltext_search", mismatch.Table)
+		assert.Equal(t, []string{"summary", "author"}, mismatch.Added)
+		assert.Equal(t, []string{"body"}, mismatch.Removed)
+		assert.Equal(t,
+			"schema mismatch for table fulltext_search (added columns: summary, author; removed columns: body)",
+			err.Error())
+	})
+
+	t.Run("changed column options", func(t *testing.T) {
+		db := openTestDB(t)
+		_, err := NewIndex[v1]("", WithDB(db))
+		assert.NoError(t, err)
+
+		type v2 struct {
+			Id    string
+			Title string
+			Body  string `hlx:",unindexed"`
+		}
+		_, err = NewIndex[v2]("", WithDB(db))
+		var mismatch *SchemaMismatchError
+		assert.True(t, errors.As(err, &mismatch))
+		assert.Equal(t, []string{"body"}, mismatch.Changed)
+	})
+
+	t.Run("changed table options", func(t *testing.T) {
+		db := openTestDB(t)
+		_, err := NewIndex[v1]("", WithDB(db), WithTokenizer(Trigram(false)), WithPrefixIndexes(2))
+		assert.NoError(t, err)
+
+		_, err = NewIndex[v1]("", WithDB(db))
+		assert.NoError(t, err)
+		_, err = NewIndex[v1]("", WithDB(db), WithTokenizer(Trigram(false)), WithPrefixIndexes(2))
+		assert.NoError(t, err)
+
+		_, err = NewIndex[v1]("", WithDB(db), WithTokenizer(Trigram(true)), WithPrefixIndexes(3))
+		var mismatch *SchemaMismatchError
+		assert.True(t, errors.As(err, &mismatch))
+		assert.Equal(t, []string{"tokenizer", "prefix"}, mismatch.Options)
+	})
+
+	t.Run("reordered fields", func(t *testing.T) {
+		db := openTestDB(t)
+		idx, err := NewIndex[v1]("", WithDB(db))
+		assert.NoError(t, err)
+		assert.NoError(t, idx.Insert(v1{Id: "1", Title: "title", Body: "body"}))
+
+		type v2 struct {
+			Body  string `hlx:",weight=5"`
+			Title string
+			Id    string
+		}
+		idx2, err := NewIndex[v2]("", WithDB(db))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"id", "title", "body"}, idx2.Fields())
+
+		doc, err := idx2.Get("1")
+		assert.NoError(t, err)
+		assert.Equal(t, v2{Id: "1", Title: "title", Body: "body"}, doc)
+
+		assert.NoError(t, idx2.Insert(v2{Id: "2", Title: "body", Body: "title"}))
+		results, err := idx2.SearchRanked("body")
+		assert.NoError(t, err)
+		assert.Len(t, results, 2)
+		assert.Equal(t, "1", results[0].Document.Id)
+	})
+
+	t.Run("legacy table", func(t *testing.T) {
+		db := openTestDB(t)
+		_, err := db.Exec("CREATE VIRTUAL TABLE fulltext_search USING FTS5(id, title)")
+		assert.NoError(t, err)
+
+		_, err = NewIndex[v1]("", WithDB(db))
+		var mismatch *SchemaMismatchError
+		assert.True(t, errors.As(err, &mismatch))
+		assert.Equal(t, []string{"body"}, mismatch.Added)
+
+		type legacy struct {
+			Id    string
+			Title string
+		}
+		_, err = NewIndex[legacy]("", WithDB(db))
+		assert.NoError(t, err)
+
+		var version int
+		assert.NoError(t, db.Get(&version, "SELECT version FROM hlx_schema WHERE table_name = 'fulltext_search'"))
+		assert.Equal(t, schemaVersion, version)
+	})
+}
@@ -0,0 +1,90 @@
+package hlx
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// migrate rebuilds the FTS5 table described by stored with the schema s.
+// The rows are copied to a new table, keeping their rowids so the ids
+// table stays valid, which then replaces the old one. Everything happens
+// in a single transaction.
+func migrate(ctx context.Context, db *sqlx.DB, stored *schemaMeta, s schema) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	tmp := s.table + "_migration"
+	if _, err := tx.ExecContext(ctx, "DROP TABLE IF EXISTS "+quoteIdent(tmp)); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, createTableQuery(quoteIdent(tmp), s)); err != nil {
+		return err
+	}
+
+	old := map[string]bool{}
+	for _, c := range stored.Columns {
+		old[c.Name] = true
+	}
+	cols := []string{"rowid"}
+	vals := []string{"rowid"}
+	for _, c := range s.columns {
+		cols = append(cols, quoteIdent(c.name))
+		switch {
+		case old[c.name]:
+			vals = append(vals, quoteIdent(c.name))
+		case c.id:
+			// The id column was renamed, the ids are still in the ids
+			// table.
+			val, err := migratedIDs(ctx, tx, s)
+			if err != nil {
+				return err
+			}
+			vals = append(vals, val)
+		default:
+			vals = append(vals, "''")
+		}
+	}
+
+	q := fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s",
+		quoteIdent(tmp), strings.Join(cols, ", "), strings.Join(vals, ", "), quoteIdent(s.table))
+	if _, err := tx.ExecContext(ctx, q); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DROP TABLE "+quoteIdent(s.table)); err != nil {
+		return err
+	}
+	q = fmt.Sprintf("ALTER TABLE %s RENAME TO %s", quoteIdent(tmp), quoteIdent(s.table))
+	if _, err := tx.ExecContext(ctx, q); err != nil {
+		return err
+	}
+
+	if err := saveMeta(ctx, tx, s.table, s.meta()); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// migratedIDs returns the expression selecting the id of every row from the
+// ids table, when the id column is renamed. Legacy tables without an ids
+// table can't be migrated.
+func migratedIDs(ctx context.Context, tx *sqlx.Tx, s schema) (string, error) {
+	var exists bool
+	err := tx.GetContext(ctx, &exists,
+		"SELECT count(*) > 0 FROM sqlite_master WHERE type = 'table' AND name = ?", s.idsTable())
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		idCol, _ := s.columns.id()
+		return "", fmt.Errorf("id column %q is not in the existing table", idCol.name)
+	}
+	return fmt.Sprintf("(SELECT id FROM %s WHERE rid = %s.rowid)", quoteIdent(s.idsTable()), quoteIdent(s.table)), nil
+}
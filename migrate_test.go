@@ -0,0 +1,114 @@
+package hlx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAutoMigrate(t *testing.T) {
+	type v1 struct {
+		Id    string
+		Title string
+		Body  string
+	}
+	type v2 struct {
+		Id      string
+		Summary string
+		Title   string `hlx:",unindexed"`
+	}
+
+	db := openTestDB(t)
+	idx, err := NewIndex[v1]("", WithDB(db))
+	assert.NoError(t, err)
+	assert.NoError(t, idx.Insert(
+		v1{Id: "1", Title: "first", Body: "lorem"},
+		v1{Id: "2", Title: "second", Body: "ipsum"},
+	))
+
+	idx2, err := NewIndex[v2]("", WithDB(db), WithAutoMigrate(), WithTokenizer(Trigram(false)))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"id", "summary", "title"}, idx2.Fields())
+
+	doc, err := idx2.Get("2")
+	assert.NoError(t, err)
+	assert.Equal(t, v2{Id: "2", Title: "second"}, doc)
+
+	// Title is no longer indexed
+	count, err := idx2.Count("second")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	assert.NoError(t, idx2.Upsert(v2{Id: "1", Summary: "migrated", Title: "first"}))
+	results, err := idx2.Search("grat")
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, "1", results[0].Id)
+
+	err = idx2.Insert(v2{Id: "2"})
+	assert.ErrorIs(t, err, ErrDuplicateID)
+
+	// The migrated schema is recorded
+	_, err = NewIndex[v2]("", WithDB(db))
+	assert.NoError(t, err)
+	_, err = NewIndex[v1]("", WithDB(db))
+	assert.ErrorIs(t, err, ErrSchemaMismatch)
+
+	var tables int
+	assert.NoError(t, db.Get(&tables, "SELECT count(*) FROM sqlite_master WHERE name LIKE '%migration%'"))
+	assert.Equal(t, 0, tables)
+}
+
+func TestAutoMigrateRenamedID(t *testing.T) {
+	type v1 struct {
+		Id    string
+		Title string
+	}
+	type v2 struct {
+		Id    string `hlx:"doc_id"`
+		Title string
+	}
+
+	db := openTestDB(t)
+	idx, err := NewIndex[v1]("", WithDB(db))
+	assert.NoError(t, err)
+	assert.NoError(t, idx.Insert(v1{Id: "a", Title: "first"}, v1{Id: "b", Title: "second"}))
+
+	idx2, err := NewIndex[v2]("", WithDB(db), WithAutoMigrate())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"doc_id", "title"}, idx2.Fields())
+
+	doc, err := idx2.Get("b")
+	assert.NoError(t, err)
+	assert.Equal(t, v2{Id: "b", Title: "second"}, doc)
+
+	results, err := idx2.Search("first")
+	assert.NoError(t, err)
+	assert.Equal(t, []v2{{Id: "a", Title: "first"}}, results)
+}
+
+func TestAutoMigrateLegacyTable(t *testing.T) {
+	db := openTestDB(t)
+	_, err := db.Exec("CREATE VIRTUAL TABLE fulltext_search USING FTS5(id, title)")
+	assert.NoError(t, err)
+	_, err = db.Exec("INSERT INTO fulltext_search VALUES ('1', 'legacy')")
+	assert.NoError(t, err)
+
+	idx, err := NewIndex[TestDoc]("", WithDB(db), WithAutoMigrate())
+	assert.NoError(t, err)
+
+	doc, err := idx.Get("1")
+	assert.NoError(t, err)
+	assert.Equal(t, TestDoc{Id: "1", Title: "legacy"}, doc)
+}
+
+func TestAutoMigrateLegacyTableWithoutID(t *testing.T) {
+	db := openTestDB(t)
+	_, err := db.Exec("CREATE VIRTUAL TABLE fulltext_search USING FTS5(key, title)")
+	assert.NoError(t, err)
+	_, err = db.Exec("INSERT INTO fulltext_search VALUES ('1', 'legacy')")
+	assert.NoError(t, err)
+
+	_, err = NewIndex[TestDoc]("", WithDB(db), WithAutoMigrate())
+	assert.ErrorContains(t, err, `id column "id" is not in the existing table`)
+}
@@ -0,0 +1,407 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	// ErrSyntax is matched by the errors of malformed query strings.
+	ErrSyntax = errors.New("syntax error")
+	// ErrUnsupported is matched by the errors of query string constructs
+	// with no FTS5 equivalent, like fuzzy or range queries.
+	ErrUnsupported = errors.New("unsupported query")
+)
+
+// ParseError is returned by ParseLucene when a query string can't be
+// translated. It unwraps to ErrSyntax, ErrUnsupported or
+// ErrUnknownColumn.
+type ParseError struct {
+	Query string
+	// Offset is the byte offset in Query of the construct that failed.
+	Offset int
+	// Msg describes the error.
+	Msg string
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%v at offset %d: %s", e.Err, e.Offset, e.Msg)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// ParseLucene translates a Lucene query string, as used by Elasticsearch
+// query_string queries, to a Query over the columns in fields, usually
+// Index.Fields(). Supported constructs are:
+//
+//	foo bar              either word, the default operator is OR
+//	foo AND bar, a && b  both words, also OR, ||, NOT and !
+//	+foo -bar            foo is required, bar is excluded
+//	"foo bar"            a phrase
+//	"foo bar"~5          the words within 5 tokens of each other
+//	foo*                 words starting with foo
+//	title:foo            foo in the title column
+//	title:(foo OR bar)   a group in the title column
+//
+// Like in Lucene, optional clauses next to required ones only affect
+// ranking. Fuzzy queries (foo~1), boosts (foo^2), ranges ([a TO b]),
+// regular expressions and wildcards other than a trailing * fail with
+// ErrUnsupported, as do queries that only exclude clauses.
+func ParseLucene(s string, fields []string) (Query, error) {
+	p := &luceneParser{input: s, fields: map[string]bool{}}
+	for _, f := range fields {
+		p.fields[strings.ToLower(f)] = true
+	}
+
+	q, err := p.parseGroup(0)
+	if err != nil {
+		return nil, err
+	}
+	if !p.eof() {
+		return nil, p.errorf(ErrSyntax, p.pos, "unexpected %q", p.input[p.pos])
+	}
+	return q, nil
+}
+
+type occur int
+
+const (
+	should occur = iota
+	must
+	mustNot
+)
+
+type clause struct {
+	occur occur
+	q     Query
+}
+
+type luceneParser struct {
+	input  string
+	pos    int
+	fields map[string]bool
+}
+
+func (p *luceneParser) errorf(err error, offset int, format string, args ...any) error {
+	return &ParseError{Query: p.input, Offset: offset, Msg: fmt.Sprintf(format, args...), Err: err}
+}
+
+func (p *luceneParser) eof() bool {
+	return p.pos >= len(p.input)
+}
+
+func (p *luceneParser) peek() byte {
+	return p.input[p.pos]
+}
+
+// space returns the size of the space character at offset n of the
+// input, 0 when there's none.
+func (p *luceneParser) space(n int) int {
+	r, size := utf8.DecodeRuneInString(p.input[n:])
+	if !unicode.IsSpace(r) {
+		return 0
+	}
+	return size
+}
+
+func (p *luceneParser) skipSpace() {
+	for !p.eof() {
+		size := p.space(p.pos)
+		if size == 0 {
+			break
+		}
+		p.pos += size
+	}
+}
+
+// keyword consumes one of the given operators when it's next in the
+// input, returning it. Word operators must be followed by a space, a
+// group or a phrase.
+func (p *luceneParser) keyword(ops ...string) string {
+	for _, op := range ops {
+		if !strings.HasPrefix(p.input[p.pos:], op) {
+			continue
+		}
+		end := p.pos + len(op)
+		if op[0] >= 'A' && op[0] <= 'Z' && end < len(p.input) &&
+			p.space(end) == 0 && p.input[end] != '(' && p.input[end] != '"' {
+			continue
+		}
+		p.pos = end
+		return op
+	}
+	return ""
+}
+
+// parseGroup parses clauses up to the end of the input, or the closing
+// parenthesis of the group started at offset start.
+func (p *luceneParser) parseGroup(start int) (Query, error) {
+	var clauses []clause
+	for {
+		p.skipSpace()
+		if p.eof() || p.peek() == ')' {
+			break
+		}
+
+		offset := p.pos
+		conj := p.keyword("AND", "&&", "OR", "||")
+		if conj != "" {
+			if len(clauses) == 0 {
+				return nil, p.errorf(ErrSyntax, offset, "%s must be between two clauses", conj)
+			}
+			p.skipSpace()
+			if p.eof() || p.peek() == ')' {
+				return nil, p.errorf(ErrSyntax, offset, "%s must be between two clauses", conj)
+			}
+		}
+
+		occ := should
+		switch p.keyword("+", "-", "!", "NOT") {
+		case "+":
+			occ = must
+		case "-", "!", "NOT":
+			occ = mustNot
+		}
+		if occ == mustNot {
+			p.skipSpace()
+		}
+
+		q, err := p.parseClause("")
+		if err != nil {
+			return nil, err
+		}
+
+		// The classic Lucene query parser rules: AND makes both clauses
+		// around it required, unless they are excluded.
+		if conj == "AND" || conj == "&&" {
+			if last := &clauses[len(clauses)-1]; last.occur == should {
+				last.occur = must
+			}
+			if occ == should {
+				occ = must
+			}
+		}
+		clauses = append(clauses, clause{occur: occ, q: q})
+	}
+
+	if len(clauses) == 0 {
+		return nil, p.errorf(ErrSyntax, start, "empty query")
+	}
+	return p.combine(clauses, start)
+}
+
+// combine returns the query matching clauses.
+func (p *luceneParser) combine(clauses []clause, start int) (Query, error) {
+	var musts, shoulds, excluded []Query
+	for _, c := range clauses {
+		switch c.occur {
+		case must:
+			musts = append(musts, c.q)
+		case should:
+			shoulds = append(shoulds, c.q)
+		case mustNot:
+			excluded = append(excluded, c.q)
+		}
+	}
+
+	var q Query
+	switch {
+	case len(musts) > 0 && len(shoulds) > 0:
+		// Optional clauses only affect ranking. They are ORed with a
+		// required clause, so they don't change which documents match but
+		// still count in the bm25 score.
+		q = And(append(musts, Or(append(shoulds, musts[0])...))...)
+	case len(musts) > 0:
+		q = And(musts...)
+	case len(shoulds) > 0:
+		q = Or(shoulds...)
+	default:
+		return nil, p.errorf(ErrUnsupported, start, "a query can't only exclude clauses")
+	}
+
+	for _, e := range excluded {
+		q = Not(q, e)
+	}
+	return q, nil
+}
+
+// parseClause parses a term, phrase or group, restricted to field when
+// it's not empty.
+func (p *luceneParser) parseClause(field string) (Query, error) {
+	if p.eof() {
+		return nil, p.errorf(ErrSyntax, p.pos, "unexpected end of query")
+	}
+
+	start := p.pos
+	var q Query
+	switch c := p.peek(); c {
+	case '(':
+		p.pos++
+		g, err := p.parseGroup(start)
+		if err != nil {
+			return nil, err
+		}
+		if p.eof() {
+			return nil, p.errorf(ErrSyntax, start, "unclosed parenthesis")
+		}
+		p.pos++
+		q = g
+	case '"':
+		phrase, err := p.parsePhrase()
+		if err != nil {
+			return nil, err
+		}
+		q = phrase
+	case '[', '{':
+		return nil, p.errorf(ErrUnsupported, start, "range queries are not supported")
+	case '/':
+		return nil, p.errorf(ErrUnsupported, start, "regular expressions are not supported")
+	case ')', ':', '^', '~', ']', '}':
+		return nil, p.errorf(ErrSyntax, start, "unexpected %q", c)
+	default:
+		text, wildcard, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		if !p.eof() && p.peek() == ':' {
+			if field != "" {
+				return nil, p.errorf(ErrSyntax, start, "field %q inside field %q", text, field)
+			}
+			if !p.fields[strings.ToLower(text)] {
+				return nil, p.errorf(ErrUnknownColumn, start, "unknown field %q", text)
+			}
+			p.pos++
+			return p.parseClause(text)
+		}
+		if err := p.termSuffix(); err != nil {
+			return nil, err
+		}
+		if wildcard {
+			q = Prefix(text)
+		} else {
+			q = Term(text)
+		}
+	}
+
+	if field != "" {
+		q = Column(field, q)
+	}
+	return q, nil
+}
+
+// parseTerm parses a bare word, returning it unescaped and whether it
+// ends with the * wildcard.
+func (p *luceneParser) parseTerm() (string, bool, error) {
+	start := p.pos
+	var b strings.Builder
+	wildcard := false
+	for !p.eof() {
+		c := p.peek()
+		if p.space(p.pos) > 0 || strings.IndexByte(`()":^~[]{}/`, c) >= 0 {
+			break
+		}
+		if wildcard {
+			return "", false, p.errorf(ErrUnsupported, start, "wildcards are only supported at the end of a word")
+		}
+		switch c {
+		case '\\':
+			p.pos++
+			if p.eof() {
+				return "", false, p.errorf(ErrSyntax, p.pos-1, "escape character at the end of the query")
+			}
+		case '*':
+			wildcard = true
+			p.pos++
+			continue
+		case '?':
+			return "", false, p.errorf(ErrUnsupported, start, "wildcards are only supported at the end of a word")
+		}
+		_, size := utf8.DecodeRuneInString(p.input[p.pos:])
+		b.WriteString(p.input[p.pos : p.pos+size])
+		p.pos += size
+	}
+
+	if b.Len() == 0 {
+		if wildcard {
+			return "", false, p.errorf(ErrUnsupported, start, "wildcards must follow a word")
+		}
+		return "", false, p.errorf(ErrSyntax, start, "expected a word")
+	}
+	return b.String(), wildcard, nil
+}
+
+// termSuffix fails if a word is followed by a fuzzy or boost operator.
+func (p *luceneParser) termSuffix() error {
+	if p.eof() {
+		return nil
+	}
+	switch p.peek() {
+	case '~':
+		return p.errorf(ErrUnsupported, p.pos, "fuzzy queries are not supported")
+	case '^':
+		return p.errorf(ErrUnsupported, p.pos, "boosts are not supported")
+	}
+	return nil
+}
+
+// parsePhrase parses a quoted phrase, with an optional ~N proximity.
+func (p *luceneParser) parsePhrase() (Query, error) {
+	start := p.pos
+	p.pos++
+	var b strings.Builder
+	for {
+		if p.eof() {
+			return nil, p.errorf(ErrSyntax, start, "unterminated phrase")
+		}
+		c := p.peek()
+		p.pos++
+		if c == '"' {
+			break
+		}
+		if c == '\\' && !p.eof() {
+			c = p.peek()
+			p.pos++
+		}
+		b.WriteByte(c)
+	}
+	words := strings.Fields(b.String())
+	if len(words) == 0 {
+		return nil, p.errorf(ErrSyntax, start, "empty phrase")
+	}
+
+	if p.eof() || p.peek() != '~' {
+		if err := p.termSuffix(); err != nil {
+			return nil, err
+		}
+		return Phrase(words...), nil
+	}
+
+	p.pos++
+	digits := p.pos
+	for !p.eof() && p.peek() >= '0' && p.peek() <= '9' {
+		p.pos++
+	}
+	distance, err := strconv.Atoi(p.input[digits:p.pos])
+	if err != nil {
+		return nil, p.errorf(ErrSyntax, digits-1, "expected the proximity distance after ~")
+	}
+	if err := p.termSuffix(); err != nil {
+		return nil, err
+	}
+
+	if distance == 0 || len(words) == 1 {
+		return Phrase(words...), nil
+	}
+	phrases := make([]PhraseQuery, len(words))
+	for n, w := range words {
+		phrases[n] = Term(w)
+	}
+	return Near(distance, phrases...), nil
+}
@@ -0,0 +1,126 @@
+package query_test
+
+import (
+	"testing"
+
+	"github.com/rubiojr/hlx"
+	"github.com/rubiojr/hlx/query"
+	"github.com/stretchr/testify/assert"
+)
+
+var luceneFields = []string{"id", "title", "content"}
+
+func TestParseLucene(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"foo", `"foo"`},
+		{"foo bar", `("foo" OR "bar")`},
+		{"foo AND bar", `("foo" AND "bar")`},
+		{"foo && bar || baz", `("foo" AND "bar" AND ("baz" OR "foo"))`},
+		{"foo OR bar", `("foo" OR "bar")`},
+		{"+must -mustnot", `("must" NOT "mustnot")`},
+		{"+must optional", `("must" AND ("optional" OR "must"))`},
+		{"foo NOT bar", `("foo" NOT "bar")`},
+		{"foo AND NOT bar !baz", `(("foo" NOT "bar") NOT "baz")`},
+		{`"foo bar"`, `"foo bar"`},
+		{`"foo bar"~5`, `NEAR("foo" "bar", 5)`},
+		{`"foo bar"~0`, `"foo bar"`},
+		{"foo*", `"foo"*`},
+		{`foo\*`, `"foo*"`},
+		{`title:foo AND content:"bar baz"~5`, `("title" : "foo" AND "content" : NEAR("bar" "baz", 5))`},
+		{"Title:(a OR b)", `"Title" : (("a" OR "b"))`},
+		{"(a OR b) AND c", `(("a" OR "b") AND "c")`},
+		{"ORANGE ANDROID", `("ORANGE" OR "ANDROID")`},
+		{`say "NEAR(" title\:x`, `("say" OR "NEAR(" OR "title:x")`},
+		{"voilà", `"voilà"`},
+		{"Å Мх", `("Å" OR "Мх")`},
+		{"café* AND \u00a0naïve", `("café"* AND "naïve")`},
+		{`title:Ørsted content:"smørrebrød à la"`, `("title" : "Ørsted" OR "content" : "smørrebrød à la")`},
+		{`\é`, `"é"`},
+	}
+
+	for _, tt := range tests {
+		q, err := query.ParseLucene(tt.in, luceneFields)
+		if assert.NoError(t, err, tt.in) {
+			assert.Equal(t, tt.want, q.String(), tt.in)
+		}
+	}
+}
+
+func TestParseLuceneErrors(t *testing.T) {
+	tests := []struct {
+		in     string
+		err    error
+		offset int
+	}{
+		{"foo~1", query.ErrUnsupported, 3},
+		{"foo~", query.ErrUnsupported, 3},
+		{"foo^2", query.ErrUnsupported, 3},
+		{`"foo bar"^2`, query.ErrUnsupported, 9},
+		{"title:[a TO b]", query.ErrUnsupported, 6},
+		{"title:{a TO b}", query.ErrUnsupported, 6},
+		{"/fo+/", query.ErrUnsupported, 0},
+		{"f?o", query.ErrUnsupported, 0},
+		{"fo*o", query.ErrUnsupported, 0},
+		{"*", query.ErrUnsupported, 0},
+		{"-foo", query.ErrUnsupported, 0},
+		{"NOT foo", query.ErrUnsupported, 0},
+		{"body:foo", query.ErrUnknownColumn, 0},
+		{"foo AND", query.ErrSyntax, 4},
+		{"AND foo", query.ErrSyntax, 0},
+		{"(foo", query.ErrSyntax, 0},
+		{"foo)", query.ErrSyntax, 3},
+		{"()", query.ErrSyntax, 0},
+		{`"foo`, query.ErrSyntax, 0},
+		{`"foo bar"~x`, query.ErrSyntax, 9},
+		{"title:", query.ErrSyntax, 6},
+		{"", query.ErrSyntax, 0},
+	}
+
+	for _, tt := range tests {
+		_, err := query.ParseLucene(tt.in, luceneFields)
+		var parseErr *query.ParseError
+		if assert.ErrorAs(t, err, &parseErr, tt.in) {
+			assert.ErrorIs(t, err, tt.err, tt.in)
+			assert.Equal(t, tt.offset, parseErr.Offset, tt.in)
+			assert.Equal(t, tt.in, parseErr.Query)
+		}
+	}
+}
+
+func TestLuceneMode(t *testing.T) {
+	idx, err := hlx.NewIndex[doc](":memory:", hlx.WithQueryMode(hlx.QueryLucene))
+	assert.NoError(t, err)
+	assert.NoError(t, idx.Insert(
+		doc{Id: "1", Title: "foo", Content: "bar and then baz"},
+		doc{Id: "2", Title: "foo", Content: "bar baz"},
+		doc{Id: "3", Title: "other", Content: "foo bar"},
+	))
+
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{`title:foo AND content:"bar baz"`, []string{"2"}},
+		{`title:foo AND content:"baz bar"~5`, []string{"1", "2"}},
+		{"+foo -title:other", []string{"1", "2"}},
+		{"+title:other baz", []string{"3"}},
+		{"then OR other", []string{"1", "3"}},
+		{"title:(oth* OR missing)", []string{"3"}},
+	}
+
+	for _, tt := range tests {
+		results, err := idx.Search(tt.in)
+		assert.NoError(t, err, tt.in)
+		var ids []string
+		for _, r := range results {
+			ids = append(ids, r.Id)
+		}
+		assert.ElementsMatch(t, tt.want, ids, tt.in)
+	}
+
+	_, err = idx.Search("foo~2")
+	assert.ErrorIs(t, err, query.ErrUnsupported)
+}
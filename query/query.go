@@ -0,0 +1,237 @@
+// Package query builds SQLite FTS5 full-text queries.
+//
+// Queries are composed from constructors instead of concatenating FTS5
+// syntax by hand. Terms are always quoted, so text coming from users is
+// matched literally and can't inject operators or column filters:
+//
+//	q := query.And(
+//		query.Column("title", query.Prefix("sea")),
+//		query.Or(query.Term("sqlite"), query.Phrase("full", "text")),
+//	)
+//	if err := query.Validate(q, idx.Fields()); err != nil {
+//		return err
+//	}
+//	results, err := idx.Search(q.String())
+package query
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrUnknownColumn is returned by Validate when a column filter uses a
+	// column that is not in the index.
+	ErrUnknownColumn = errors.New("unknown column")
+	// ErrEmptyQuery is returned by Validate when a query, or part of it,
+	// matches nothing because it is empty.
+	ErrEmptyQuery = errors.New("empty query")
+)
+
+// Query is an FTS5 query expression.
+type Query interface {
+	// String renders the query as an FTS5 MATCH expression.
+	String() string
+	validate(fields map[string]bool) error
+}
+
+// PhraseQuery is a query matching a sequence of tokens, the only kind of
+// query accepted by Near.
+type PhraseQuery interface {
+	Query
+	phrase()
+}
+
+// Validate checks that q can be rendered as a valid FTS5 expression, and
+// that the columns it filters on are in fields, usually Index.Fields().
+// Column names are case-insensitive.
+func Validate(q Query, fields []string) error {
+	if q == nil {
+		return ErrEmptyQuery
+	}
+	known := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		known[strings.ToLower(f)] = true
+	}
+	return q.validate(known)
+}
+
+type phrase struct {
+	text   string
+	prefix bool
+}
+
+// Term matches a single word. The word is quoted, so FTS5 operators and
+// special characters in it are matched literally.
+func Term(word string) PhraseQuery {
+	return phrase{text: word}
+}
+
+// Phrase matches the words in order, next to each other.
+func Phrase(words ...string) PhraseQuery {
+	return phrase{text: strings.Join(words, " ")}
+}
+
+// Prefix matches the words starting with prefix.
+func Prefix(prefix string) PhraseQuery {
+	return phrase{text: prefix, prefix: true}
+}
+
+func (p phrase) String() string {
+	s := quote(p.text)
+	if p.prefix {
+		s += "*"
+	}
+	return s
+}
+
+func (p phrase) validate(map[string]bool) error {
+	if strings.TrimSpace(p.text) == "" {
+		return ErrEmptyQuery
+	}
+	return nil
+}
+
+func (phrase) phrase() {}
+
+type group struct {
+	op      string
+	queries []Query
+}
+
+// And matches the documents matching all the queries.
+func And(queries ...Query) Query {
+	return group{op: "AND", queries: queries}
+}
+
+// Or matches the documents matching any of the queries.
+func Or(queries ...Query) Query {
+	return group{op: "OR", queries: queries}
+}
+
+func (g group) String() string {
+	if len(g.queries) == 1 {
+		return g.queries[0].String()
+	}
+	parts := make([]string, len(g.queries))
+	for n, q := range g.queries {
+		parts[n] = q.String()
+	}
+	return "(" + strings.Join(parts, " "+g.op+" ") + ")"
+}
+
+func (g group) validate(fields map[string]bool) error {
+	if len(g.queries) == 0 {
+		return fmt.Errorf("%w: %s without queries", ErrEmptyQuery, g.op)
+	}
+	for _, q := range g.queries {
+		if q == nil {
+			return ErrEmptyQuery
+		}
+		if err := q.validate(fields); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type not struct {
+	q, excluded Query
+}
+
+// Not matches the documents matching q but not excluded. FTS5 has no
+// unary NOT, a query can't match only the documents that don't match
+// another one.
+func Not(q, excluded Query) Query {
+	return not{q: q, excluded: excluded}
+}
+
+func (n not) String() string {
+	return "(" + n.q.String() + " NOT " + n.excluded.String() + ")"
+}
+
+func (n not) validate(fields map[string]bool) error {
+	if n.q == nil || n.excluded == nil {
+		return ErrEmptyQuery
+	}
+	if err := n.q.validate(fields); err != nil {
+		return err
+	}
+	return n.excluded.validate(fields)
+}
+
+type near struct {
+	distance int
+	phrases  []PhraseQuery
+}
+
+// Near matches the documents where the phrases appear within distance
+// tokens of each other. A distance of zero or less uses the FTS5 default,
+// 10.
+func Near(distance int, phrases ...PhraseQuery) Query {
+	return near{distance: distance, phrases: phrases}
+}
+
+func (n near) String() string {
+	parts := make([]string, len(n.phrases))
+	for i, p := range n.phrases {
+		parts[i] = p.String()
+	}
+	s := "NEAR(" + strings.Join(parts, " ")
+	if n.distance > 0 {
+		s += ", " + strconv.Itoa(n.distance)
+	}
+	return s + ")"
+}
+
+func (n near) validate(fields map[string]bool) error {
+	if len(n.phrases) == 0 {
+		return fmt.Errorf("%w: NEAR without phrases", ErrEmptyQuery)
+	}
+	for _, p := range n.phrases {
+		if p == nil {
+			return ErrEmptyQuery
+		}
+		if err := p.validate(fields); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type column struct {
+	name string
+	q    Query
+}
+
+// Column restricts q to the column name, one of the names returned by
+// Index.Fields().
+func Column(name string, q Query) Query {
+	return column{name: name, q: q}
+}
+
+func (c column) String() string {
+	s := quote(c.name) + " : "
+	switch c.q.(type) {
+	case phrase, near:
+		return s + c.q.String()
+	}
+	return s + "(" + c.q.String() + ")"
+}
+
+func (c column) validate(fields map[string]bool) error {
+	if !fields[strings.ToLower(c.name)] {
+		return fmt.Errorf("%w %q", ErrUnknownColumn, c.name)
+	}
+	if c.q == nil {
+		return ErrEmptyQuery
+	}
+	return c.q.validate(fields)
+}
+
+// quote returns s as an FTS5 string, matched literally.
+func quote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
@@ -0,0 +1,96 @@
+package query_test
+
+import (
+	"testing"
+
+	"github.com/rubiojr/hlx"
+	"github.com/rubiojr/hlx/query"
+	"github.com/stretchr/testify/assert"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type doc struct {
+	Id      string
+	Title   string
+	Content string
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		q    query.Query
+		want string
+	}{
+		{query.Term("hello"), `"hello"`},
+		{query.Term(`say "hi"`), `"say ""hi"""`},
+		{query.Term("title:x OR NEAR"), `"title:x OR NEAR"`},
+		{query.Phrase("full", "text"), `"full text"`},
+		{query.Prefix("sea"), `"sea"*`},
+		{query.And(query.Term("a")), `"a"`},
+		{query.And(query.Term("a"), query.Term("b")), `("a" AND "b")`},
+		{query.Or(query.Term("a"), query.And(query.Term("b"), query.Term("c"))), `("a" OR ("b" AND "c"))`},
+		{query.Not(query.Term("a"), query.Term("b")), `("a" NOT "b")`},
+		{query.Near(0, query.Term("a"), query.Phrase("b", "c")), `NEAR("a" "b c")`},
+		{query.Near(5, query.Term("a"), query.Prefix("b")), `NEAR("a" "b"*, 5)`},
+		{query.Column("title", query.Term("a")), `"title" : "a"`},
+		{query.Column("title", query.Or(query.Term("a"), query.Term("b"))), `"title" : (("a" OR "b"))`},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tt.q.String())
+	}
+}
+
+func TestValidate(t *testing.T) {
+	fields := []string{"id", "title", "content"}
+
+	assert.NoError(t, query.Validate(query.Column("Title", query.Term("a")), fields))
+	assert.ErrorIs(t, query.Validate(query.Column("body", query.Term("a")), fields), query.ErrUnknownColumn)
+	assert.ErrorIs(t, query.Validate(query.And(query.Term("a"), query.Column("body", query.Term("a"))), fields), query.ErrUnknownColumn)
+	assert.ErrorIs(t, query.Validate(query.Term(" "), fields), query.ErrEmptyQuery)
+	assert.ErrorIs(t, query.Validate(query.Or(), fields), query.ErrEmptyQuery)
+	assert.ErrorIs(t, query.Validate(query.Near(2), fields), query.ErrEmptyQuery)
+	assert.ErrorIs(t, query.Validate(query.Not(query.Term("a"), nil), fields), query.ErrEmptyQuery)
+	assert.ErrorIs(t, query.Validate(nil, fields), query.ErrEmptyQuery)
+}
+
+func TestSearch(t *testing.T) {
+	idx, err := hlx.NewIndex[doc](":memory:")
+	assert.NoError(t, err)
+	assert.NoError(t, idx.Insert(
+		doc{Id: "1", Title: "SQLite full-text search", Content: "FTS5 makes searching text fast"},
+		doc{Id: "2", Title: "Go generics", Content: `type parameters, "quoted" OR NEAR text`},
+		doc{Id: "3", Title: "Searching with Go", Content: "full text search in Go with SQLite"},
+	))
+
+	tests := []struct {
+		name string
+		q    query.Query
+		want []string
+	}{
+		{"term", query.Term("generics"), []string{"2"}},
+		{"operators are literal", query.Term("OR"), []string{"2"}},
+		{"quotes", query.Phrase(`"quoted"`, "or", "near"), []string{"2"}},
+		{"phrase", query.Phrase("full", "text", "search"), []string{"1", "3"}},
+		{"phrase order", query.Phrase("search", "text"), nil},
+		{"prefix", query.Column("title", query.Prefix("search")), []string{"1", "3"}},
+		{"and", query.And(query.Term("go"), query.Term("sqlite")), []string{"3"}},
+		{"or", query.Or(query.Term("generics"), query.Term("fts5")), []string{"1", "2"}},
+		{"not", query.Not(query.Term("sqlite"), query.Column("title", query.Term("go"))), []string{"1"}},
+		{"near", query.Near(2, query.Term("search"), query.Term("go")), []string{"3"}},
+		{"column group", query.Column("content", query.Or(query.Term("fast"), query.Term("parameters"))), []string{"1", "2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.NoError(t, query.Validate(tt.q, idx.Fields()))
+			results, err := idx.Search(tt.q.String())
+			assert.NoError(t, err)
+			var ids []string
+			for _, r := range results {
+				ids = append(ids, r.Id)
+			}
+			assert.ElementsMatch(t, tt.want, ids)
+		})
+	}
+}
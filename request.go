@@ -0,0 +1,498 @@
+package hlx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/rubiojr/hlx/query"
+)
+
+var ErrInvalidRequest = fmt.Errorf("invalid search request")
+
+// SearchRequest is a search described as JSON, modeled after the
+// Elasticsearch query DSL, and executed with Execute:
+//
+//	{
+//	  "bool": {
+//	    "must": [{"match": {"title": "sqlite"}}],
+//	    "must_not": [{"match_phrase": {"content": "deprecated api"}}],
+//	    "filter": [{"term": {"status": "published"}}, {"range": {"views": {"gte": 100}}}]
+//	  },
+//	  "size": 10,
+//	  "from": 0,
+//	  "highlight": {"fields": ["title"]}
+//	}
+//
+// The query clause is given by the clause keys of the object, or by a
+// "query" key holding a clause. Text clauses compile to the FTS5 MATCH
+// expression, filter clauses to SQL predicates on the stored values of
+// the columns, usually unindexed ones, so both are applied by the same
+// SQL query.
+type SearchRequest struct {
+	Query Clause `json:"query"`
+	// Size is the maximum number of results. Zero means no limit.
+	Size int `json:"size,omitempty"`
+	// From skips the given number of results.
+	From int `json:"from,omitempty"`
+	// Cursor resumes the search after the last result of a previous page.
+	Cursor    string            `json:"cursor,omitempty"`
+	Highlight *HighlightOptions `json:"highlight,omitempty"`
+	Snippet   *SnippetOptions   `json:"snippet,omitempty"`
+}
+
+var requestKeys = []string{"query", "size", "from", "cursor", "highlight", "snippet"}
+
+func (r *SearchRequest) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	clause := map[string]json.RawMessage{}
+	for key, value := range raw {
+		if !slices.Contains(requestKeys, key) {
+			clause[key] = value
+			delete(raw, key)
+		}
+	}
+	if q, ok := raw["query"]; ok {
+		delete(raw, "query")
+		if len(clause) > 0 {
+			return fmt.Errorf("%w: query can't be combined with top level clauses", ErrInvalidRequest)
+		}
+		if err := json.Unmarshal(q, &r.Query); err != nil {
+			return err
+		}
+	} else if len(clause) > 0 {
+		b, err := json.Marshal(clause)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(b, &r.Query); err != nil {
+			return err
+		}
+	}
+
+	type options SearchRequest
+	opts := (*options)(r)
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, opts)
+}
+
+// Clause is a query clause. Exactly one of its fields must be set, and
+// the maps must have a single field name key.
+type Clause struct {
+	// Bool combines clauses.
+	Bool *BoolClause `json:"bool,omitempty"`
+	// Match matches the documents with any of the words of the text in
+	// the field.
+	Match map[string]string `json:"match,omitempty"`
+	// MatchPhrase matches the documents with the words of the text in the
+	// field, in order, next to each other.
+	MatchPhrase map[string]string `json:"match_phrase,omitempty"`
+	// Prefix matches the documents with words starting with the text in
+	// the field.
+	Prefix map[string]string `json:"prefix,omitempty"`
+	// QueryString matches a Lucene query string, see query.ParseLucene.
+	QueryString *QueryStringClause `json:"query_string,omitempty"`
+	// SimpleQueryString matches free text, see QuerySimple.
+	SimpleQueryString *QueryStringClause `json:"simple_query_string,omitempty"`
+	// Term filters the documents whose field equals the value, or is null.
+	Term map[string]any `json:"term,omitempty"`
+	// Terms filters the documents whose field equals one of the values,
+	// null values matching null fields.
+	Terms map[string][]any `json:"terms,omitempty"`
+	// Range filters the documents whose field is within the bounds.
+	Range map[string]RangeClause `json:"range,omitempty"`
+}
+
+func (c *Clause) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if len(raw) != 1 {
+		return fmt.Errorf("%w: a clause must have exactly one key, got %d", ErrInvalidRequest, len(raw))
+	}
+
+	type clause Clause
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode((*clause)(c)); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+	}
+	return nil
+}
+
+// BoolClause combines clauses like the Elasticsearch bool query.
+type BoolClause struct {
+	// Must clauses must all match.
+	Must []Clause `json:"must,omitempty"`
+	// Should clauses are optional when there are Must or Filter clauses,
+	// and only affect ranking. Otherwise at least one must match.
+	Should []Clause `json:"should,omitempty"`
+	// MustNot clauses must not match.
+	MustNot []Clause `json:"must_not,omitempty"`
+	// Filter clauses must all match. Text clauses still affect ranking,
+	// FTS5 can't match text without scoring it.
+	Filter []Clause `json:"filter,omitempty"`
+}
+
+// QueryStringClause is a query string matched against every column.
+type QueryStringClause struct {
+	Query string `json:"query"`
+}
+
+// RangeClause holds the bounds of a range clause. Numeric bounds compare
+// the values as numbers, other bounds compare the stored text.
+type RangeClause struct {
+	Gt  any `json:"gt,omitempty"`
+	Gte any `json:"gte,omitempty"`
+	Lt  any `json:"lt,omitempty"`
+	Lte any `json:"lte,omitempty"`
+}
+
+// Execute runs the search described by req, best matches first. Requests
+// without text clauses return the documents in insertion order. Invalid
+// requests fail with an error matching ErrInvalidRequest.
+func (i *index[K]) Execute(req SearchRequest) (SearchPage[K], error) {
+	return i.ExecuteContext(context.Background(), req)
+}
+
+func (i *index[K]) ExecuteContext(ctx context.Context, req SearchRequest) (SearchPage[K], error) {
+	if err := i.checkOptions(req); err != nil {
+		return SearchPage[K]{}, err
+	}
+	c, err := i.compile(req.Query)
+	if err != nil {
+		return SearchPage[K]{}, err
+	}
+	if c.empty() {
+		return SearchPage[K]{}, fmt.Errorf("%w: empty query", ErrInvalidRequest)
+	}
+
+	var match string
+	if c.match != nil {
+		if err := query.Validate(c.match, i.fields); err != nil {
+			return SearchPage[K]{}, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+		}
+		match = c.match.String()
+	}
+	return i.search(ctx, match, SearchOptions{
+		Filters:   c.filters,
+		Limit:     req.Size,
+		Offset:    req.From,
+		Cursor:    req.Cursor,
+		Highlight: req.Highlight,
+		Snippet:   req.Snippet,
+	})
+}
+
+// checkOptions fails when the paging, highlight or snippet options of req
+// would be rejected by search.
+func (i *index[K]) checkOptions(req SearchRequest) error {
+	if req.Size < 0 || req.From < 0 {
+		return fmt.Errorf("%w: size and from must not be negative", ErrInvalidRequest)
+	}
+	if req.Cursor != "" {
+		if req.From > 0 {
+			return fmt.Errorf("%w: cursor and from can't be combined", ErrInvalidRequest)
+		}
+		if _, err := decodeCursor(req.Cursor); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+		}
+	}
+	if req.Highlight != nil {
+		for _, field := range req.Highlight.Fields {
+			if err := i.checkField(field); err != nil {
+				return err
+			}
+		}
+	}
+	if s := req.Snippet; s != nil {
+		if s.Field != "" {
+			if err := i.checkField(s.Field); err != nil {
+				return err
+			}
+		}
+		if s.Tokens < 0 || s.Tokens > 64 {
+			return fmt.Errorf("%w: snippet tokens must be between 1 and 64", ErrInvalidRequest)
+		}
+	}
+	return nil
+}
+
+// compiled is a clause compiled to a full-text query, and filters.
+type compiled struct {
+	match   query.Query
+	filters []Filter
+}
+
+func (c compiled) empty() bool {
+	return c.match == nil && len(c.filters) == 0
+}
+
+func (i *index[K]) compile(c Clause) (compiled, error) {
+	var out compiled
+	kinds := 0
+	if c.Bool != nil {
+		kinds++
+		b, err := i.compileBool(c.Bool)
+		if err != nil {
+			return out, err
+		}
+		out = b
+	}
+	for _, text := range []struct {
+		m   map[string]string
+		new func(field, text string) (query.Query, error)
+	}{
+		{c.Match, matchClause},
+		{c.MatchPhrase, matchPhraseClause},
+		{c.Prefix, prefixClause},
+	} {
+		if text.m == nil {
+			continue
+		}
+		kinds++
+		field, value, err := single(text.m)
+		if err != nil {
+			return out, err
+		}
+		if err := i.checkField(field); err != nil {
+			return out, err
+		}
+		if out.match, err = text.new(field, value); err != nil {
+			return out, err
+		}
+	}
+	if c.QueryString != nil {
+		kinds++
+		q, err := query.ParseLucene(c.QueryString.Query, i.fields)
+		if err != nil {
+			return out, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+		}
+		out.match = q
+	}
+	if c.SimpleQueryString != nil {
+		kinds++
+		out.match = simpleQuery(c.SimpleQueryString.Query)
+		if out.match == nil {
+			return out, fmt.Errorf("%w: simple_query_string without words to match", ErrInvalidRequest)
+		}
+	}
+	if c.Term != nil {
+		kinds++
+		field, value, err := single(c.Term)
+		if err == nil {
+			err = i.checkField(field)
+		}
+		if err != nil {
+			return out, err
+		}
+		if err := checkValue(field, value); err != nil {
+			return out, err
+		}
+		out.filters = []Filter{Eq(field, value)}
+	}
+	if c.Terms != nil {
+		kinds++
+		field, values, err := single(c.Terms)
+		if err == nil {
+			err = i.checkField(field)
+		}
+		if err != nil {
+			return out, err
+		}
+		for _, value := range values {
+			if err := checkValue(field, value); err != nil {
+				return out, err
+			}
+		}
+		out.filters = []Filter{In(field, values...)}
+	}
+	if c.Range != nil {
+		kinds++
+		field, r, err := single(c.Range)
+		if err == nil {
+			err = i.checkField(field)
+		}
+		if err != nil {
+			return out, err
+		}
+		bounds := 0
+		for _, value := range []any{r.Gt, r.Gte, r.Lt, r.Lte} {
+			if value == nil {
+				continue
+			}
+			bounds++
+			if err := checkValue(field, value); err != nil {
+				return out, err
+			}
+		}
+		if bounds == 0 {
+			return out, fmt.Errorf("%w: range on %q without bounds", ErrInvalidRequest, field)
+		}
+		out.filters = []Filter{rangeFilter{field: field, gt: r.Gt, gte: r.Gte, lt: r.Lt, lte: r.Lte}}
+	}
+
+	if kinds != 1 {
+		return out, fmt.Errorf("%w: a clause must have exactly one kind, got %d", ErrInvalidRequest, kinds)
+	}
+	return out, nil
+}
+
+func (i *index[K]) compileBool(b *BoolClause) (compiled, error) {
+	var out compiled
+	var musts, shoulds, excluded []query.Query
+	var shouldFilters []Filter
+	for _, c := range slices.Concat(b.Must, b.Filter) {
+		sub, err := i.compile(c)
+		if err != nil {
+			return out, err
+		}
+		if sub.match != nil {
+			musts = append(musts, sub.match)
+		}
+		out.filters = append(out.filters, sub.filters...)
+	}
+	for _, c := range b.Should {
+		sub, err := i.compile(c)
+		if err != nil {
+			return out, err
+		}
+		if sub.match != nil && len(sub.filters) > 0 {
+			return out, fmt.Errorf("%w: should clauses can't combine text and filters", ErrInvalidRequest)
+		}
+		if sub.match != nil {
+			shoulds = append(shoulds, sub.match)
+		} else {
+			shouldFilters = append(shouldFilters, allFilter(sub.filters))
+		}
+	}
+	if len(shoulds) > 0 && len(shouldFilters) > 0 {
+		return out, fmt.Errorf("%w: should clauses can't combine text and filters", ErrInvalidRequest)
+	}
+	for _, c := range b.MustNot {
+		sub, err := i.compile(c)
+		if err != nil {
+			return out, err
+		}
+		if sub.match != nil && len(sub.filters) > 0 {
+			return out, fmt.Errorf("%w: must_not clauses can't combine text and filters", ErrInvalidRequest)
+		}
+		if sub.match != nil {
+			excluded = append(excluded, sub.match)
+		} else {
+			out.filters = append(out.filters, Not(allFilter(sub.filters)))
+		}
+	}
+
+	required := len(musts) > 0 || len(out.filters) > 0
+	switch {
+	case len(musts) > 0 && len(shoulds) > 0:
+		// Optional clauses only affect ranking, see query.ParseLucene.
+		out.match = query.And(append(musts, query.Or(append(shoulds, musts[0])...))...)
+	case len(musts) > 0:
+		out.match = query.And(musts...)
+	case len(shoulds) > 0 && !required:
+		out.match = query.Or(shoulds...)
+	case len(shoulds) > 0:
+		// Optional clauses can't affect ranking without a required text
+		// clause to OR them with.
+	}
+	if len(shouldFilters) > 0 && !required {
+		out.filters = append(out.filters, AnyOf(shouldFilters...))
+	}
+
+	if len(excluded) > 0 {
+		if out.match == nil {
+			return out, fmt.Errorf("%w: must_not text clauses need a must or should text clause", ErrInvalidRequest)
+		}
+		for _, e := range excluded {
+			out.match = query.Not(out.match, e)
+		}
+	}
+	return out, nil
+}
+
+// allFilter returns a filter matching all filters.
+func allFilter(filters []Filter) Filter {
+	if len(filters) == 1 {
+		return filters[0]
+	}
+	return AllOf(filters...)
+}
+
+func (i *index[K]) checkField(field string) error {
+	if i.columnIndex(field) < 0 {
+		return fmt.Errorf("%w: unknown field %q", ErrInvalidRequest, field)
+	}
+	return nil
+}
+
+// checkValue fails unless value is nil or a scalar stored like document
+// fields are, see encodeValue. JSON objects and arrays aren't.
+func checkValue(field string, value any) error {
+	if value == nil {
+		return nil
+	}
+	t := reflect.TypeOf(value)
+	if t == timeType || t.Implements(valuerType) || t.Implements(textMarshalerType) {
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return nil
+	}
+	return fmt.Errorf("%w: value of %q must be a string, number or boolean", ErrInvalidRequest, field)
+}
+
+func single[V any](m map[string]V) (string, V, error) {
+	var zero V
+	if len(m) != 1 {
+		return "", zero, fmt.Errorf("%w: expected a single field, got %d", ErrInvalidRequest, len(m))
+	}
+	for field, v := range m {
+		return field, v, nil
+	}
+	return "", zero, nil
+}
+
+func matchClause(field, text string) (query.Query, error) {
+	var words []query.Query
+	for _, w := range strings.Fields(text) {
+		if strings.IndexFunc(w, isWordChar) >= 0 {
+			words = append(words, query.Term(w))
+		}
+	}
+	if len(words) == 0 {
+		return nil, fmt.Errorf("%w: match on %s without words", ErrInvalidRequest, field)
+	}
+	return query.Column(field, query.Or(words...)), nil
+}
+
+func matchPhraseClause(field, text string) (query.Query, error) {
+	if strings.IndexFunc(text, isWordChar) < 0 {
+		return nil, fmt.Errorf("%w: match_phrase on %s without words", ErrInvalidRequest, field)
+	}
+	return query.Column(field, query.Phrase(strings.Fields(text)...)), nil
+}
+
+func prefixClause(field, text string) (query.Query, error) {
+	if strings.IndexFunc(text, isWordChar) < 0 {
+		return nil, fmt.Errorf("%w: prefix on %s without words", ErrInvalidRequest, field)
+	}
+	return query.Column(field, query.Prefix(text)), nil
+}
@@ -0,0 +1,178 @@
+package hlx
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type article struct {
+	Id        string
+	Title     string
+	Content   string
+	Status    string    `hlx:",unindexed"`
+	Views     int       `hlx:",unindexed"`
+	Published time.Time `hlx:",unindexed"`
+	Tags      []string  `hlx:",unindexed"`
+	Editor    *string   `hlx:",unindexed"`
+}
+
+// jan returns the given day of January 2024.
+func jan(day int) time.Time {
+	return time.Date(2024, 1, day, 0, 0, 0, 0, time.UTC)
+}
+
+func newArticleIndex(t *testing.T) Index[article] {
+	t.Helper()
+	idx, err := NewIndex[article](":memory:")
+	assert.NoError(t, err)
+	editor := "alice"
+	assert.NoError(t, idx.Insert(
+		article{Id: "1", Title: "SQLite full-text search", Content: "fts5 tables", Status: "published", Views: 900,
+			Published: jan(1), Tags: []string{"sqlite", "fts"}, Editor: &editor},
+		article{Id: "2", Title: "SQLite internals", Content: "deprecated api and btrees", Status: "published", Views: 50,
+			Published: jan(5), Tags: []string{"sqlite"}},
+		article{Id: "3", Title: "Go and SQLite", Content: "database/sql drivers", Status: "draft", Views: 120,
+			Published: jan(10), Tags: []string{"go", "sqlite"}},
+		article{Id: "4", Title: "Go generics", Content: "type parameters", Status: "published", Views: 10,
+			Published: jan(20), Editor: &editor},
+	))
+	return idx
+}
+
+// articleIDs returns the ids of the documents in results.
+func articleIDs(results []SearchResult[article]) []string {
+	var ids []string
+	for _, r := range results {
+		ids = append(ids, r.Document.Id)
+	}
+	return ids
+}
+
+func execute(t *testing.T, idx Index[article], js string) ([]string, error) {
+	t.Helper()
+	var req SearchRequest
+	if err := json.Unmarshal([]byte(js), &req); err != nil {
+		return nil, err
+	}
+	page, err := idx.Execute(req)
+	return articleIDs(page.Results), err
+}
+
+func TestExecute(t *testing.T) {
+	idx := newArticleIndex(t)
+
+	tests := []struct {
+		name string
+		req  string
+		want []string
+	}{
+		{"match", `{"match": {"title": "sqlite go"}}`, []string{"1", "2", "3", "4"}},
+		{"query key", `{"query": {"match": {"title": "generics"}}}`, []string{"4"}},
+		{"match phrase", `{"match_phrase": {"title": "full text"}}`, []string{"1"}},
+		{"prefix", `{"prefix": {"title": "gen"}}`, []string{"4"}},
+		{"query string", `{"query_string": {"query": "title:sqlite AND -content:fts5"}}`, []string{"2", "3"}},
+		{"simple query string", `{"simple_query_string": {"query": "sqlite -go"}}`, []string{"1", "2"}},
+		{"bool", `{"bool": {
+			"must": [{"match": {"title": "sqlite"}}],
+			"must_not": [{"match_phrase": {"content": "deprecated api"}}],
+			"filter": [{"term": {"status": "published"}}]
+		}}`, []string{"1"}},
+		{"range", `{"bool": {
+			"must": [{"match": {"title": "sqlite"}}],
+			"filter": [{"range": {"views": {"gte": 100, "lt": 900}}}]
+		}}`, []string{"3"}},
+		{"filters only", `{"bool": {"filter": [{"terms": {"status": ["draft", "archived"]}}]}}`, []string{"3"}},
+		{"terms null", `{"terms": {"status": ["draft", null]}}`, []string{"3"}},
+		{"term null", `{"term": {"status": null}}`, nil},
+		{"should filters", `{"bool": {"should": [{"term": {"status": "draft"}}, {"range": {"views": {"gt": 500}}}]}}`, []string{"1", "3"}},
+		{"must not filter", `{"bool": {"must": [{"match": {"title": "sqlite"}}], "must_not": [{"term": {"status": "draft"}}]}}`, []string{"1", "2"}},
+		{"optional should", `{"bool": {"must": [{"match": {"title": "go"}}], "should": [{"match": {"content": "drivers"}}]}}`, []string{"3", "4"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids, err := execute(t, idx, tt.req)
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, tt.want, ids)
+		})
+	}
+}
+
+func TestExecuteOptions(t *testing.T) {
+	idx := newArticleIndex(t)
+
+	var req SearchRequest
+	assert.NoError(t, json.Unmarshal([]byte(`{
+		"match": {"title": "sqlite"},
+		"size": 1,
+		"highlight": {"fields": ["title"], "open": "[", "close": "]"},
+		"snippet": {"field": "content", "tokens": 2}
+	}`), &req))
+	assert.Equal(t, 1, req.Size)
+
+	page, err := idx.Execute(req)
+	assert.NoError(t, err)
+	assert.Len(t, page.Results, 1)
+	assert.Contains(t, page.Results[0].Highlights["title"], "[SQLite]")
+	assert.NotEmpty(t, page.Results[0].Snippet)
+	assert.NotEmpty(t, page.NextCursor)
+
+	seen := []string{page.Results[0].Document.Id}
+	for page.NextCursor != "" {
+		req.Cursor = page.NextCursor
+		page, err = idx.Execute(req)
+		assert.NoError(t, err)
+		for _, r := range page.Results {
+			seen = append(seen, r.Document.Id)
+		}
+	}
+	assert.ElementsMatch(t, []string{"1", "2", "3"}, seen)
+
+	req = SearchRequest{Query: Clause{Term: map[string]any{"status": "published"}}, Size: 2, From: 1}
+	page, err = idx.Execute(req)
+	assert.NoError(t, err)
+	assert.Len(t, page.Results, 2)
+	assert.Equal(t, "2", page.Results[0].Document.Id)
+
+	b, err := json.Marshal(req)
+	assert.NoError(t, err)
+	var decoded SearchRequest
+	assert.NoError(t, json.Unmarshal(b, &decoded))
+	assert.Equal(t, req, decoded)
+}
+
+func TestExecuteErrors(t *testing.T) {
+	idx := newArticleIndex(t)
+
+	for _, req := range []string{
+		`{}`,
+		`{"match": {"body": "x"}}`,
+		`{"match": {"title": "x", "content": "y"}}`,
+		`{"match": {"title": "!!"}}`,
+		`{"term": {"missing": 1}}`,
+		`{"unknown": {"title": "x"}}`,
+		`{"bool": {"musts": []}}`,
+		`{"bool": {"must_not": [{"match": {"title": "x"}}]}}`,
+		`{"bool": {"should": [{"match": {"title": "x"}}, {"term": {"status": "draft"}}]}}`,
+		`{"query_string": {"query": "foo~2"}}`,
+		`{"query": {"match": {"title": "x"}}, "term": {"status": "draft"}}`,
+		`{"match": {"title": "x"}, "size": -1}`,
+		`{"match": {"title": "x"}, "from": -1}`,
+		`{"match": {"title": "x"}, "cursor": "!"}`,
+		`{"match": {"title": "x"}, "cursor": "MDox", "from": 1}`,
+		`{"match": {"title": "x"}, "highlight": {"fields": ["missing"]}}`,
+		`{"match": {"title": "x"}, "snippet": {"field": "missing"}}`,
+		`{"match": {"title": "x"}, "snippet": {"tokens": 65}}`,
+		`{"term": {"status": {"a": 1}}}`,
+		`{"term": {"status": ["draft"]}}`,
+		`{"terms": {"status": ["draft", {"a": 1}]}}`,
+		`{"range": {"views": {"gte": [1]}}}`,
+		`{"range": {"views": {"gte": null}}}`,
+	} {
+		_, err := execute(t, idx, req)
+		assert.ErrorIs(t, err, ErrInvalidRequest, req)
+	}
+}
@@ -0,0 +1,237 @@
+package hlx
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// tagName is the struct tag key used to configure how a field is indexed.
+//
+// The tag value is a comma separated list. The first element renames the
+// column (empty keeps the default), the remaining elements are options:
+//
+//	Title string `hlx:"headline"`          // stored in the "headline" column
+//	Path  string `hlx:"-"`                 // not stored at all
+//	Hash  string `hlx:",unindexed"`        // stored, but not searchable
+//	Body  string `hlx:"body,weight=2.5"`   // bm25 weight used when ranking
+const tagName = "hlx"
+
+// dbTagName is the struct tag key used by sqlx. Fields without a name in
+// their hlx tag use the name in their db tag, and db:"-" skips a field,
+// so documents map to the same columns in hlx and sqlx.
+const dbTagName = "db"
+
+const defaultWeight = 1.0
+
+// schema describes the tables backing an index.
+type schema struct {
+	table     string
+	columns   columns
+	tokenizer Tokenizer
+	prefixes  []int
+}
+
+// idsTable is the name of the table mapping document ids to rowids.
+func (s schema) idsTable() string {
+	return s.table + "_ids"
+}
+
+// column describes how a struct field maps to a column of the FTS5 table.
+type column struct {
+	name string
+	// index is the index sequence of the struct field, as used by
+	// reflect.Value.FieldByIndex. Fields of embedded structs have more
+	// than one element.
+	index     []int
+	id        bool
+	unindexed bool
+	weight    float64
+}
+
+type columns []column
+
+func (c columns) names() []string {
+	names := make([]string, len(c))
+	for i, col := range c {
+		names[i] = col.name
+	}
+	return names
+}
+
+func (c columns) setWeights(weights map[string]float64) error {
+	for name, w := range weights {
+		if w < 0 {
+			return fmt.Errorf("invalid weight for column %s: %v", name, w)
+		}
+		found := false
+		for n := range c {
+			if c[n].name == name {
+				c[n].weight = w
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("unknown column %q", name)
+		}
+	}
+
+	return nil
+}
+
+func (c columns) id() (column, bool) {
+	for _, col := range c {
+		if col.id {
+			return col, true
+		}
+	}
+	return column{}, false
+}
+
+// parseColumns returns the FTS5 columns for the struct type t, honoring
+// the hlx and db struct tags. Fields without a tagged name are named with
+// mapper, strings.ToLower when nil.
+//
+// Unexported fields are skipped and the fields of embedded structs are
+// flattened into columns. As with Go field promotion, a field hides the
+// fields with the same column name nested deeper in embedded structs.
+func parseColumns(t reflect.Type, mapper func(string) string) (columns, error) {
+	if mapper == nil {
+		mapper = strings.ToLower
+	}
+
+	var all columns
+	if err := appendColumns(&all, t, nil, mapper); err != nil {
+		return nil, err
+	}
+
+	depth := map[string]int{}
+	for _, col := range all {
+		if d, ok := depth[col.name]; !ok || len(col.index) < d {
+			depth[col.name] = len(col.index)
+		}
+	}
+
+	cols := make(columns, 0, len(all))
+	seen := map[string]bool{}
+	for _, col := range all {
+		if len(col.index) > depth[col.name] {
+			continue
+		}
+		if seen[col.name] {
+			return nil, fmt.Errorf("duplicate column %q", col.name)
+		}
+		seen[col.name] = true
+		cols = append(cols, col)
+	}
+
+	return cols, nil
+}
+
+func appendColumns(cols *columns, t reflect.Type, index []int, mapper func(string) string) error {
+	for i := range t.NumField() {
+		field := t.Field(i)
+		fieldIndex := append(slices.Clone(index), i)
+		if field.Anonymous {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			_, tagged := field.Tag.Lookup(tagName)
+			_, dbTagged := field.Tag.Lookup(dbTagName)
+			if !tagged && !dbTagged && isEmbeddedStruct(ft) {
+				if !field.IsExported() && field.Type.Kind() == reflect.Pointer {
+					// Unexported embedded pointers can't be allocated
+					// when scanning.
+					continue
+				}
+				if err := appendColumns(cols, ft, fieldIndex, mapper); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		col, skip, err := parseTag(field, mapper)
+		if err != nil {
+			return err
+		}
+		if skip {
+			continue
+		}
+		col.index = fieldIndex
+		col.id = isIDField(field)
+		*cols = append(*cols, col)
+	}
+
+	return nil
+}
+
+// isEmbeddedStruct reports whether an embedded field of type t has its
+// fields flattened into columns, rather than being stored as a column.
+func isEmbeddedStruct(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct || t == timeType {
+		return false
+	}
+	pt := reflect.PointerTo(t)
+	return !pt.Implements(scannerType) && !pt.Implements(textUnmarshalerType)
+}
+
+func parseTag(field reflect.StructField, mapper func(string) string) (column, bool, error) {
+	col := column{name: strings.ToLower(mapper(field.Name)), weight: defaultWeight}
+	dbName, _, _ := strings.Cut(field.Tag.Get(dbTagName), ",")
+	tag, ok := field.Tag.Lookup(tagName)
+	switch dbName {
+	case "":
+	case "-":
+		// The hlx tag takes precedence, and the Id field is always stored.
+		if !ok && !isIDField(field) {
+			return col, true, nil
+		}
+	default:
+		col.name = strings.ToLower(dbName)
+	}
+	if !ok {
+		return col, false, nil
+	}
+
+	if tag == "-" {
+		if isIDField(field) {
+			return col, false, fmt.Errorf("field %s can not be skipped", field.Name)
+		}
+		return col, true, nil
+	}
+
+	parts := strings.Split(tag, ",")
+	if name := strings.TrimSpace(parts[0]); name != "" {
+		col.name = strings.ToLower(name)
+	}
+
+	for _, opt := range parts[1:] {
+		opt = strings.TrimSpace(opt)
+		switch {
+		case opt == "":
+		case opt == "unindexed":
+			col.unindexed = true
+		case strings.HasPrefix(opt, "weight="):
+			w, err := strconv.ParseFloat(strings.TrimPrefix(opt, "weight="), 64)
+			if err != nil || w < 0 {
+				return col, false, fmt.Errorf("invalid weight for field %s: %q", field.Name, opt)
+			}
+			col.weight = w
+		default:
+			return col, false, fmt.Errorf("unknown %s tag option for field %s: %q", tagName, field.Name, opt)
+		}
+	}
+
+	return col, false, nil
+}
+
+func isIDField(field reflect.StructField) bool {
+	return strings.ToLower(field.Name) == "id"
+}
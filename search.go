@@ -0,0 +1,335 @@
+package hlx
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SearchResult is a document matching a query along with its relevance.
+type SearchResult[K any] struct {
+	Document K
+	// Score is the negated bm25 rank of the document, computed with the
+	// configured column weights. Higher scores are better matches.
+	Score float64
+	// Highlights holds the highlighted text of the fields requested with
+	// SearchOptions.Highlight, keyed by field name.
+	Highlights map[string]string
+	// Snippet is the text fragment requested with SearchOptions.Snippet.
+	Snippet string
+}
+
+// SearchOptions controls which page of the ranked results is returned.
+type SearchOptions struct {
+	// Limit is the maximum number of results returned. Zero means no limit.
+	Limit int
+	// Offset skips the given number of results.
+	Offset int
+	// Cursor resumes the search after the last result of a previous page,
+	// see SearchPage.NextCursor. It can't be combined with Offset.
+	Cursor string
+	// Highlight, when set, returns the highlighted text of the matching
+	// fields in SearchResult.Highlights.
+	Highlight *HighlightOptions
+	// Snippet, when set, returns a fragment of text around the matches in
+	// SearchResult.Snippet.
+	Snippet *SnippetOptions
+	// Mode selects how the query is interpreted. Defaults to the mode of
+	// the index, see WithQueryMode.
+	Mode QueryMode
+	// Filters restricts the results to the documents matching all of the
+	// filters, see Filter. With an empty query, every document matching
+	// the filters is returned, in insertion order.
+	Filters []Filter
+}
+
+// HighlightOptions configures the FTS5 highlight() function.
+type HighlightOptions struct {
+	// Fields to highlight, as returned by Fields(). Defaults to every
+	// field.
+	Fields []string `json:"fields,omitempty"`
+	// Open and Close are inserted around every matched term. Default to
+	// "<b>" and "</b>".
+	Open  string `json:"open,omitempty"`
+	Close string `json:"close,omitempty"`
+}
+
+// SnippetOptions configures the FTS5 snippet() function.
+type SnippetOptions struct {
+	// Field the snippet is extracted from, as returned by Fields(). When
+	// empty, the field that best matches the query is used.
+	Field string `json:"field,omitempty"`
+	// Open and Close are inserted around every matched term. Default to
+	// "<b>" and "</b>".
+	Open  string `json:"open,omitempty"`
+	Close string `json:"close,omitempty"`
+	// Ellipsis is added where the text is truncated. Defaults to "...".
+	Ellipsis string `json:"ellipsis,omitempty"`
+	// Tokens is the maximum number of tokens in the snippet, between 1
+	// and 64. Defaults to 16.
+	Tokens int `json:"tokens,omitempty"`
+}
+
+const (
+	defaultOpenMarker   = "<b>"
+	defaultCloseMarker  = "</b>"
+	defaultEllipsis     = "..."
+	defaultSnippetWidth = 16
+)
+
+// SearchPage is a page of ranked search results.
+type SearchPage[K any] struct {
+	Results []SearchResult[K]
+	// NextCursor is an opaque token that fetches the following page when
+	// passed as SearchOptions.Cursor. Empty when there are no more results.
+	NextCursor string
+}
+
+// cursor is the position of a result in the rank, rowid ordering.
+type cursor struct {
+	rank  float64
+	rowid int64
+}
+
+func (c cursor) encode() string {
+	s := strconv.FormatFloat(c.rank, 'g', -1, 64) + ":" + strconv.FormatInt(c.rowid, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+func decodeCursor(token string) (cursor, error) {
+	var c cursor
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+
+	rank, rowid, ok := strings.Cut(string(b), ":")
+	if !ok {
+		return c, ErrInvalidCursor
+	}
+
+	c.rank, err = strconv.ParseFloat(rank, 64)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	c.rowid, err = strconv.ParseInt(rowid, 10, 64)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+
+	return c, nil
+}
+
+// SearchRanked returns the documents matching query, best matches first.
+func (i *index[K]) SearchRanked(query string) ([]SearchResult[K], error) {
+	return i.SearchRankedContext(context.Background(), query)
+}
+
+func (i *index[K]) SearchRankedContext(ctx context.Context, query string) ([]SearchResult[K], error) {
+	page, err := i.SearchWithOptionsContext(ctx, query, SearchOptions{})
+	return page.Results, err
+}
+
+// SearchWithOptions returns a page of the documents matching query, best
+// matches first. Results with the same rank are ordered by rowid, so pages
+// are stable.
+func (i *index[K]) SearchWithOptions(query string, opts SearchOptions) (SearchPage[K], error) {
+	return i.SearchWithOptionsContext(context.Background(), query, opts)
+}
+
+func (i *index[K]) SearchWithOptionsContext(ctx context.Context, query string, opts SearchOptions) (SearchPage[K], error) {
+	if len(opts.Filters) > 0 && strings.TrimSpace(query) == "" {
+		return i.search(ctx, "", opts)
+	}
+	match, err := i.matchQuery(query, opts.Mode)
+	if err != nil || match == "" {
+		return SearchPage[K]{}, err
+	}
+	return i.search(ctx, match, opts)
+}
+
+// search returns a page of the documents matching the FTS5 expression
+// match and opts.Filters. Without match, every document is a match, with
+// the same score, and they are sorted in insertion order.
+func (i *index[K]) search(ctx context.Context, match string, opts SearchOptions) (SearchPage[K], error) {
+	var page SearchPage[K]
+	if opts.Limit < 0 || opts.Offset < 0 {
+		return page, fmt.Errorf("limit and offset must not be negative")
+	}
+	if opts.Cursor != "" && opts.Offset > 0 {
+		return page, fmt.Errorf("cursor and offset can't be combined")
+	}
+
+	exprs, args, err := i.auxiliaryExprs(opts)
+	if err != nil {
+		return page, err
+	}
+
+	rank := "rank"
+	if match == "" {
+		rank = "0.0"
+	}
+	where, whereArgs, err := i.matchWhere(match, opts.Filters)
+	if err != nil {
+		return page, err
+	}
+	args = append(args, whereArgs...)
+	if opts.Cursor != "" {
+		c, err := decodeCursor(opts.Cursor)
+		if err != nil {
+			return page, err
+		}
+		where = append(where, fmt.Sprintf("(%[1]s > ? OR (%[1]s = ? AND rowid > ?))", rank))
+		args = append(args, c.rank, c.rank, c.rowid)
+	}
+
+	q := fmt.Sprintf("SELECT %s, %s, rowid%s FROM %s", i.selectCols, rank, exprs, i.table)
+	if len(where) > 0 {
+		q += " WHERE " + strings.Join(where, " AND ")
+	}
+	q += fmt.Sprintf(" ORDER BY %s, rowid", rank)
+
+	// One more row than requested tells whether there's a next page.
+	limit := -1
+	if opts.Limit > 0 {
+		limit = opts.Limit + 1
+	}
+	q += " LIMIT ? OFFSET ?"
+	args = append(args, limit, opts.Offset)
+
+	rows, err := i.db.QueryxContext(ctx, q, args...)
+	if err != nil {
+		return page, i.queryError(err, match)
+	}
+	defer rows.Close()
+
+	var last cursor
+	var snippet sql.NullString
+	var highlights []sql.NullString
+	if opts.Highlight != nil {
+		highlights = make([]sql.NullString, len(i.highlightFields(opts.Highlight)))
+	}
+	extra := []any{&last.rank, &last.rowid}
+	for n := range highlights {
+		extra = append(extra, &highlights[n])
+	}
+	if opts.Snippet != nil {
+		extra = append(extra, &snippet)
+	}
+
+	for rows.Next() {
+		if opts.Limit > 0 && len(page.Results) == opts.Limit {
+			// last still holds the position of the last result.
+			page.NextCursor = last.encode()
+			break
+		}
+		doc, err := i.scan(rows, extra...)
+		if err != nil {
+			return page, fmt.Errorf("struct scan failed: %w", err)
+		}
+		result := SearchResult[K]{Document: doc, Score: -last.rank, Snippet: snippet.String}
+		if opts.Highlight != nil {
+			result.Highlights = make(map[string]string, len(highlights))
+			for n, field := range i.highlightFields(opts.Highlight) {
+				result.Highlights[field] = highlights[n].String
+			}
+		}
+		page.Results = append(page.Results, result)
+	}
+	if err := rows.Err(); err != nil {
+		return page, i.queryError(err, match)
+	}
+
+	return page, nil
+}
+
+// matchWhere returns the predicates selecting the documents matching the
+// FTS5 expression match, when it's not empty, and filters.
+func (i *index[K]) matchWhere(match string, filters []Filter) ([]string, []any, error) {
+	var where []string
+	var args []any
+	if match != "" {
+		where = append(where, i.table+" MATCH ?")
+		args = append(args, match)
+	}
+	if len(filters) > 0 {
+		pred, filterArgs, err := i.where(filters)
+		if err != nil {
+			return nil, nil, err
+		}
+		where = append(where, pred)
+		args = append(args, filterArgs...)
+	}
+	return where, args, nil
+}
+
+// auxiliaryExprs returns the highlight() and snippet() expressions to
+// select, and their arguments.
+func (i *index[K]) auxiliaryExprs(opts SearchOptions) (string, []any, error) {
+	var exprs string
+	var args []any
+
+	if h := opts.Highlight; h != nil {
+		open, close := markers(h.Open, h.Close)
+		for _, field := range i.highlightFields(h) {
+			n := i.columnIndex(field)
+			if n < 0 {
+				return "", nil, fmt.Errorf("unknown field %q", field)
+			}
+			exprs += fmt.Sprintf(", highlight(%s, %d, ?, ?)", i.table, n)
+			args = append(args, open, close)
+		}
+	}
+
+	if s := opts.Snippet; s != nil {
+		n := -1
+		if s.Field != "" {
+			n = i.columnIndex(s.Field)
+			if n < 0 {
+				return "", nil, fmt.Errorf("unknown field %q", s.Field)
+			}
+		}
+		tokens := s.Tokens
+		if tokens == 0 {
+			tokens = defaultSnippetWidth
+		}
+		if tokens < 1 || tokens > 64 {
+			return "", nil, fmt.Errorf("snippet tokens must be between 1 and 64")
+		}
+		ellipsis := s.Ellipsis
+		if ellipsis == "" {
+			ellipsis = defaultEllipsis
+		}
+		open, close := markers(s.Open, s.Close)
+		exprs += fmt.Sprintf(", snippet(%s, %d, ?, ?, ?, %d)", i.table, n, tokens)
+		args = append(args, open, close, ellipsis)
+	}
+
+	return exprs, args, nil
+}
+
+func (i *index[K]) highlightFields(h *HighlightOptions) []string {
+	if len(h.Fields) == 0 {
+		return i.fields
+	}
+	return h.Fields
+}
+
+func (i *index[K]) columnIndex(field string) int {
+	for n, name := range i.fields {
+		if name == field {
+			return n
+		}
+	}
+	return -1
+}
+
+func markers(open, close string) (string, string) {
+	if open == "" && close == "" {
+		return defaultOpenMarker, defaultCloseMarker
+	}
+	return open, close
+}
@@ -0,0 +1,219 @@
+package hlx
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchRanked(t *testing.T) {
+	idx, err := NewIndex[TestDoc](":memory:")
+	assert.NoError(t, err)
+
+	err = idx.Insert(
+		TestDoc{Id: "weak", Title: "Unrelated", Content: "a long text that mentions golang only once among many other words"},
+		TestDoc{Id: "strong", Title: "golang", Content: "golang golang"},
+	)
+	assert.NoError(t, err)
+
+	results, err := idx.SearchRanked("golang")
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+	assert.Equal(t, "strong", results[0].Document.Id)
+	assert.Equal(t, "weak", results[1].Document.Id)
+	assert.Greater(t, results[0].Score, results[1].Score)
+
+	results, err = idx.SearchRanked("nonexistent")
+	assert.NoError(t, err)
+	assert.Len(t, results, 0)
+}
+
+func TestSearchRankedWeights(t *testing.T) {
+	docs := []TestDoc{
+		{Id: "title", Title: "sqlite", Content: "a database"},
+		{Id: "content", Title: "a database", Content: "sqlite"},
+	}
+
+	t.Run("title boosted", func(t *testing.T) {
+		idx, err := NewIndex[TestDoc](":memory:", WithWeights(map[string]float64{"title": 10}))
+		assert.NoError(t, err)
+		assert.NoError(t, idx.Insert(docs...))
+
+		results, err := idx.SearchRanked("sqlite")
+		assert.NoError(t, err)
+		assert.Len(t, results, 2)
+		assert.Equal(t, "title", results[0].Document.Id)
+	})
+
+	t.Run("content boosted", func(t *testing.T) {
+		idx, err := NewIndex[TestDoc](":memory:", WithWeights(map[string]float64{"content": 10}))
+		assert.NoError(t, err)
+		assert.NoError(t, idx.Insert(docs...))
+
+		results, err := idx.SearchRanked("sqlite")
+		assert.NoError(t, err)
+		assert.Len(t, results, 2)
+		assert.Equal(t, "content", results[0].Document.Id)
+	})
+
+	t.Run("struct tag weights", func(t *testing.T) {
+		type doc struct {
+			Id      string
+			Title   string
+			Content string `hlx:",weight=10"`
+		}
+		idx, err := NewIndex[doc](":memory:")
+		assert.NoError(t, err)
+		assert.NoError(t, idx.Insert(
+			doc{Id: "title", Title: "sqlite", Content: "a database"},
+			doc{Id: "content", Title: "a database", Content: "sqlite"},
+		))
+
+		results, err := idx.SearchRanked("sqlite")
+		assert.NoError(t, err)
+		assert.Len(t, results, 2)
+		assert.Equal(t, "content", results[0].Document.Id)
+	})
+
+	t.Run("unknown column", func(t *testing.T) {
+		_, err := NewIndex[TestDoc](":memory:", WithWeights(map[string]float64{"nope": 2}))
+		assert.Error(t, err)
+	})
+}
+
+func TestSearchWithOptions(t *testing.T) {
+	idx, err := NewIndex[TestDoc](":memory:")
+	assert.NoError(t, err)
+
+	var docs []TestDoc
+	for n := range 25 {
+		docs = append(docs, TestDoc{
+			Id:      fmt.Sprintf("%02d", n),
+			Title:   "page",
+			Content: strings.Repeat("filler ", n%5),
+		})
+	}
+	assert.NoError(t, idx.Insert(docs...))
+
+	all, err := idx.SearchRanked("page")
+	assert.NoError(t, err)
+	assert.Len(t, all, 25)
+
+	t.Run("limit and offset", func(t *testing.T) {
+		page, err := idx.SearchWithOptions("page", SearchOptions{Limit: 10, Offset: 20})
+		assert.NoError(t, err)
+		assert.Len(t, page.Results, 5)
+		assert.Equal(t, all[20:], page.Results)
+		assert.Empty(t, page.NextCursor)
+	})
+
+	t.Run("cursor", func(t *testing.T) {
+		var seen []SearchResult[TestDoc]
+		opts := SearchOptions{Limit: 10}
+		for {
+			page, err := idx.SearchWithOptions("page", opts)
+			assert.NoError(t, err)
+			seen = append(seen, page.Results...)
+			if page.NextCursor == "" {
+				break
+			}
+			opts.Cursor = page.NextCursor
+		}
+		assert.Equal(t, all, seen)
+	})
+
+	t.Run("full last page", func(t *testing.T) {
+		page, err := idx.SearchWithOptions("page", SearchOptions{Limit: 5, Offset: 20})
+		assert.NoError(t, err)
+		assert.Equal(t, all[20:], page.Results)
+		assert.Empty(t, page.NextCursor)
+
+		page, err = idx.SearchWithOptions("page", SearchOptions{Limit: 25})
+		assert.NoError(t, err)
+		assert.Len(t, page.Results, 25)
+		assert.Empty(t, page.NextCursor)
+
+		page, err = idx.SearchWithOptions("page", SearchOptions{Limit: 24})
+		assert.NoError(t, err)
+		assert.NotEmpty(t, page.NextCursor)
+		page, err = idx.SearchWithOptions("page", SearchOptions{Limit: 1, Cursor: page.NextCursor})
+		assert.NoError(t, err)
+		assert.Equal(t, all[24:], page.Results)
+		assert.Empty(t, page.NextCursor)
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		_, err := idx.SearchWithOptions("page", SearchOptions{Cursor: "not a cursor"})
+		assert.ErrorIs(t, err, ErrInvalidCursor)
+
+		page, err := idx.SearchWithOptions("page", SearchOptions{Limit: 1})
+		assert.NoError(t, err)
+		_, err = idx.SearchWithOptions("page", SearchOptions{Cursor: page.NextCursor, Offset: 1})
+		assert.Error(t, err)
+
+		_, err = idx.SearchWithOptions("page", SearchOptions{Limit: -1})
+		assert.Error(t, err)
+	})
+}
+
+func TestSearchHighlightAndSnippet(t *testing.T) {
+	idx, err := NewIndex[TestDoc](":memory:")
+	assert.NoError(t, err)
+
+	err = idx.Insert(TestDoc{
+		Id:      "1",
+		Title:   "Full text search",
+		Content: "SQLite ships with a full text search engine called FTS5 that supports ranking, highlighting and snippets out of the box",
+	})
+	assert.NoError(t, err)
+
+	t.Run("highlight", func(t *testing.T) {
+		page, err := idx.SearchWithOptions("search", SearchOptions{
+			Highlight: &HighlightOptions{Fields: []string{"title", "content"}, Open: "[", Close: "]"},
+		})
+		assert.NoError(t, err)
+		assert.Len(t, page.Results, 1)
+		assert.Equal(t, map[string]string{
+			"title":   "Full text [search]",
+			"content": "SQLite ships with a full text [search] engine called FTS5 that supports ranking, highlighting and snippets out of the box",
+		}, page.Results[0].Highlights)
+		assert.Equal(t, "Full text search", page.Results[0].Document.Title)
+	})
+
+	t.Run("highlight defaults to every field", func(t *testing.T) {
+		page, err := idx.SearchWithOptions("title:search", SearchOptions{Highlight: &HighlightOptions{}})
+		assert.NoError(t, err)
+		assert.Len(t, page.Results, 1)
+		assert.Len(t, page.Results[0].Highlights, len(idx.Fields()))
+		assert.Equal(t, "Full text <b>search</b>", page.Results[0].Highlights["title"])
+	})
+
+	t.Run("snippet", func(t *testing.T) {
+		page, err := idx.SearchWithOptions("ranking", SearchOptions{
+			Snippet: &SnippetOptions{Field: "content", Ellipsis: "…", Tokens: 5},
+		})
+		assert.NoError(t, err)
+		assert.Len(t, page.Results, 1)
+		assert.Equal(t, "…that supports <b>ranking</b>, highlighting and…", page.Results[0].Snippet)
+	})
+
+	t.Run("snippet from best field", func(t *testing.T) {
+		page, err := idx.SearchWithOptions("snippets", SearchOptions{Snippet: &SnippetOptions{Tokens: 3}})
+		assert.NoError(t, err)
+		assert.Len(t, page.Results, 1)
+		assert.Contains(t, page.Results[0].Snippet, "<b>snippets</b>")
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		_, err := idx.SearchWithOptions("search", SearchOptions{Highlight: &HighlightOptions{Fields: []string{"nope"}}})
+		assert.Error(t, err)
+
+		_, err = idx.SearchWithOptions("search", SearchOptions{Snippet: &SnippetOptions{Field: "nope"}})
+		assert.Error(t, err)
+
+		_, err = idx.SearchWithOptions("search", SearchOptions{Snippet: &SnippetOptions{Tokens: 65}})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,121 @@
+package hlx
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"github.com/rubiojr/hlx/query"
+)
+
+// QueryMode selects how search queries are interpreted.
+type QueryMode int
+
+const (
+	// QueryDefault uses the mode of the index, set with WithQueryMode.
+	QueryDefault QueryMode = iota
+	// QueryFTS5 interprets queries as FTS5 query syntax, the default.
+	QueryFTS5
+	// QuerySimple interprets queries as free text typed by users, which
+	// never produces a syntax error. Every word must match, words are
+	// matched literally, and the only operators are:
+	//
+	//	+word       the word must match, same as a plain word
+	//	-word       the word must not match
+	//	"a phrase"  the words must match in order, next to each other
+	QuerySimple
+	// QueryLucene interprets queries as Lucene query strings, like the
+	// Elasticsearch query_string query, see query.ParseLucene. Constructs
+	// with no FTS5 equivalent fail with a *query.ParseError.
+	QueryLucene
+)
+
+// SearchUserInput returns the documents matching the free text input,
+// best matches first, interpreting it like QuerySimple does. Use it to
+// pass text typed in public search boxes.
+func (i *index[K]) SearchUserInput(input string) ([]SearchResult[K], error) {
+	return i.SearchUserInputContext(context.Background(), input)
+}
+
+func (i *index[K]) SearchUserInputContext(ctx context.Context, input string) ([]SearchResult[K], error) {
+	page, err := i.SearchWithOptionsContext(ctx, input, SearchOptions{Mode: QuerySimple})
+	return page.Results, err
+}
+
+// matchQuery returns the FTS5 expression for q in the given mode, empty
+// when it matches nothing.
+func (i *index[K]) matchQuery(q string, mode QueryMode) (string, error) {
+	if mode == QueryDefault {
+		mode = i.queryMode
+	}
+	switch mode {
+	case QuerySimple:
+		if parsed := simpleQuery(q); parsed != nil {
+			return parsed.String(), nil
+		}
+		return "", nil
+	case QueryLucene:
+		parsed, err := query.ParseLucene(q, i.fields)
+		if err != nil {
+			return "", err
+		}
+		return parsed.String(), nil
+	}
+	return q, nil
+}
+
+// simpleQuery converts free text to a query, quoting every word and
+// phrase. It returns nil when no word must match, FTS5 can't express
+// queries that only exclude words.
+func simpleQuery(input string) query.Query {
+	var required, excluded []query.Query
+	for {
+		input = strings.TrimLeftFunc(input, unicode.IsSpace)
+		if input == "" {
+			break
+		}
+
+		op := input[0]
+		if op == '+' || op == '-' {
+			input = input[1:]
+		}
+
+		var text string
+		var q query.Query
+		if rest, ok := strings.CutPrefix(input, `"`); ok {
+			// An unterminated phrase runs to the end of the input.
+			text, input, _ = strings.Cut(rest, `"`)
+			q = query.Phrase(text)
+		} else {
+			end := strings.IndexFunc(input, unicode.IsSpace)
+			if end < 0 {
+				end = len(input)
+			}
+			text, input = input[:end], input[end:]
+			q = query.Term(text)
+		}
+
+		// Text without letters or numbers has no tokens to match.
+		if strings.IndexFunc(text, isWordChar) < 0 {
+			continue
+		}
+		if op == '-' {
+			excluded = append(excluded, q)
+		} else {
+			required = append(required, q)
+		}
+	}
+
+	if len(required) == 0 {
+		return nil
+	}
+	q := query.And(required...)
+	for _, e := range excluded {
+		q = query.Not(q, e)
+	}
+	return q
+}
+
+func isWordChar(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r)
+}
@@ -0,0 +1,96 @@
+package hlx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSimpleQuery(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"", ""},
+		{"   ", ""},
+		{"hello", `"hello"`},
+		{"hello world", `("hello" AND "world")`},
+		{"+hello -world", `("hello" NOT "world")`},
+		{`"hello world" foo`, `("hello world" AND "foo")`},
+		{`-"hello world" foo -bar`, `(("foo" NOT "hello world") NOT "bar")`},
+		{`"unterminated phrase`, `"unterminated phrase"`},
+		{`title:foo NEAR(a b) OR`, `("title:foo" AND "NEAR(a" AND "b)" AND "OR")`},
+		{`say"hi"`, `"say""hi"""`},
+		{"full-text", `"full-text"`},
+		{`- + "" ( ) * -foo`, ""},
+	}
+
+	for _, tt := range tests {
+		var got string
+		if q := simpleQuery(tt.input); q != nil {
+			got = q.String()
+		}
+		assert.Equal(t, tt.want, got, tt.input)
+	}
+}
+
+func TestSearchUserInput(t *testing.T) {
+	idx, err := NewIndex[TestDoc](":memory:")
+	assert.NoError(t, err)
+	assert.NoError(t, idx.Insert(
+		TestDoc{Id: "1", Title: "Full-text search", Content: "quick brown fox"},
+		TestDoc{Id: "2", Title: "Title: NEAR operators", Content: "brown bear"},
+		TestDoc{Id: "3", Title: "Other", Content: "fox and bear"},
+	))
+
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"brown", []string{"1", "2"}},
+		{"brown -fox", []string{"2"}},
+		{"+fox +bear", []string{"3"}},
+		{`"fox brown"`, nil},
+		{`"quick brown"`, []string{"1"}},
+		{"title: near(", []string{"2"}},
+		{`full-text "`, []string{"1"}},
+		{`"`, nil},
+		{"-fox", nil},
+		{"AND OR NOT", nil},
+		{"*", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			results, err := idx.SearchUserInput(tt.input)
+			assert.NoError(t, err)
+			var ids []string
+			for _, r := range results {
+				ids = append(ids, r.Document.Id)
+			}
+			assert.ElementsMatch(t, tt.want, ids)
+		})
+	}
+}
+
+func TestWithQueryMode(t *testing.T) {
+	idx, err := NewIndex[TestDoc](":memory:", WithQueryMode(QuerySimple))
+	assert.NoError(t, err)
+	assert.NoError(t, idx.Insert(TestDoc{Id: "1", Title: "hello world", Content: "Content: OR"}))
+
+	results, err := idx.Search(`content: OR "`)
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+
+	count, err := idx.Count("hello -world")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	page, err := idx.SearchWithOptions("title:hello", SearchOptions{Mode: QueryFTS5})
+	assert.NoError(t, err)
+	assert.Len(t, page.Results, 1)
+
+	completions, err := idx.Autocomplete("hel", 5)
+	assert.NoError(t, err)
+	assert.Len(t, completions, 1)
+}
@@ -0,0 +1,125 @@
+package hlx
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// FTS5 error messages. Drivers add their own prefixes and suffixes, like
+// modernc.org/sqlite's "SQL logic error: ... (1)", so they aren't
+// anchored.
+var (
+	syntaxErrorRe     = regexp.MustCompile(`fts5: syntax error near "(.*)"`)
+	noSuchColumnRe    = regexp.MustCompile(`no such column: (\S+)`)
+	specialQueryRe    = regexp.MustCompile(`unknown special query: (\S*)`)
+	expectedIntegerRe = regexp.MustCompile(`expected integer, got "(.*)"`)
+)
+
+// queryError returns a *QuerySyntaxError when err is SQLite rejecting the
+// FTS5 query, err otherwise.
+func (i *index[K]) queryError(err error, query string) error {
+	if e := parseQueryError(err, query, i.fields); e != nil {
+		return e
+	}
+	return err
+}
+
+// parseQueryError returns the *QuerySyntaxError described by err, nil
+// when err isn't an FTS5 query error.
+func parseQueryError(err error, query string, fields []string) *QuerySyntaxError {
+	if err == nil {
+		return nil
+	}
+
+	msg := err.Error()
+	e := &QuerySyntaxError{Query: query, Err: err}
+	if m := syntaxErrorRe.FindStringSubmatch(msg); m != nil {
+		e.Token = m[1]
+		switch strings.ToUpper(e.Token) {
+		case "":
+			e.Hint = "unexpected end of query, an operator, group or column filter is incomplete"
+		case "AND", "OR", "NOT":
+			e.Hint = fmt.Sprintf("%s must be between two expressions", e.Token)
+		case ")":
+			e.Hint = "unexpected closing parenthesis"
+		default:
+			e.Hint = fmt.Sprintf(`unexpected %q, quote text to search it literally, e.g. "c++"`, e.Token)
+		}
+	} else if strings.Contains(msg, "unterminated string") {
+		e.Token = `"`
+		e.Offset = unterminatedOffset(query)
+		e.Hint = `unterminated string, close the quote or double it ("") to search for it`
+		return e
+	} else if m := noSuchColumnRe.FindStringSubmatch(msg); m != nil {
+		e.Token = m[1]
+		e.Hint = fmt.Sprintf("unknown column %q, the columns are %s", e.Token, strings.Join(fields, ", "))
+	} else if m := specialQueryRe.FindStringSubmatch(msg); m != nil {
+		e.Token = "*" + m[1]
+		e.Hint = `"*" must follow a word, e.g. search*`
+	} else if m := expectedIntegerRe.FindStringSubmatch(msg); m != nil {
+		e.Token = m[1]
+		e.Hint = "the NEAR distance must be an integer, e.g. NEAR(a b, 5)"
+	} else {
+		return nil
+	}
+
+	e.Offset = tokenOffset(query, e.Token)
+	return e
+}
+
+// tokenOffset returns the byte offset of the first occurrence of token in
+// query outside quoted strings, len(query) for an empty token and -1 when
+// it's not found. Bareword tokens only match whole barewords.
+func tokenOffset(query, token string) int {
+	if token == "" {
+		return len(query)
+	}
+
+	word := isBareword(token[0])
+	quoted := false
+	for n := 0; n < len(query); n++ {
+		if query[n] == '"' {
+			// Escaped quotes, "", toggle twice.
+			quoted = !quoted
+			continue
+		}
+		if quoted || !strings.HasPrefix(query[n:], token) {
+			continue
+		}
+		end := n + len(token)
+		if word && (n > 0 && isBareword(query[n-1]) || end < len(query) && isBareword(query[end])) {
+			continue
+		}
+		return n
+	}
+
+	return -1
+}
+
+// unterminatedOffset returns the byte offset of the quote opening the
+// unterminated string in query, -1 if there's none.
+func unterminatedOffset(query string) int {
+	open := -1
+	for n := 0; n < len(query); n++ {
+		if query[n] != '"' {
+			continue
+		}
+		switch {
+		case open < 0:
+			open = n
+		case n+1 < len(query) && query[n+1] == '"':
+			// An escaped quote inside the string.
+			n++
+		default:
+			open = -1
+		}
+	}
+	return open
+}
+
+// isBareword reports whether c can be part of an FTS5 bareword.
+func isBareword(c byte) bool {
+	return c >= 0x80 || c == '_' || c == 0x1a ||
+		'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
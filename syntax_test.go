@@ -0,0 +1,72 @@
+package hlx
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuerySyntaxError(t *testing.T) {
+	idx, err := NewIndex[TestDoc](":memory:")
+	assert.NoError(t, err)
+	assert.NoError(t, idx.Insert(TestDoc{Title: "hello"}))
+
+	tests := []struct {
+		query  string
+		token  string
+		offset int
+		hint   string
+	}{
+		{"hello AND", "", 9, "unexpected end of query"},
+		{"hello AND AND", "AND", 6, "AND must be between two expressions"},
+		{"ANDROID OR", "", 10, "unexpected end of query"},
+		{"hello world)", ")", 11, "unexpected closing parenthesis"},
+		{`"c++" c++`, "+", 7, `unexpected "+"`},
+		{`say "hi`, `"`, 4, "unterminated string"},
+		{`"a""b`, `"`, 0, "unterminated string"},
+		{"body:hello", "body", 0, `unknown column "body", the columns are id, title, description, content`},
+		{"*hello", "*hello", 0, `"*" must follow a word`},
+		{"NEAR(a b, x)", "x", 10, "NEAR distance must be an integer"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			_, err := idx.Search(tt.query)
+			var syntaxErr *QuerySyntaxError
+			if !assert.ErrorAs(t, err, &syntaxErr) {
+				return
+			}
+			assert.ErrorIs(t, err, ErrQuerySyntax)
+			assert.Equal(t, tt.query, syntaxErr.Query)
+			assert.Equal(t, tt.token, syntaxErr.Token)
+			assert.Equal(t, tt.offset, syntaxErr.Offset)
+			assert.Contains(t, syntaxErr.Hint, tt.hint)
+			assert.NotNil(t, errors.Unwrap(err))
+		})
+	}
+
+	t.Run("every search method", func(t *testing.T) {
+		_, err := idx.Count("hello AND")
+		assert.ErrorIs(t, err, ErrQuerySyntax)
+		_, err = idx.SearchRanked("hello AND")
+		assert.ErrorIs(t, err, ErrQuerySyntax)
+		for _, err := range idx.SearchIter("hello AND") {
+			assert.ErrorIs(t, err, ErrQuerySyntax)
+		}
+	})
+}
+
+func TestParseQueryError(t *testing.T) {
+	// modernc.org/sqlite wraps SQLite messages.
+	e := parseQueryError(errors.New(`SQL logic error: fts5: syntax error near "+" (1)`), "c++", nil)
+	assert.Equal(t, "+", e.Token)
+	assert.Equal(t, 1, e.Offset)
+
+	e = parseQueryError(errors.New("SQL logic error: no such column: body (1)"), "x body:y", nil)
+	assert.Equal(t, "body", e.Token)
+	assert.Equal(t, 2, e.Offset)
+
+	assert.Nil(t, parseQueryError(errors.New("database is locked"), "hello", nil))
+	assert.Nil(t, parseQueryError(nil, "hello", nil))
+}
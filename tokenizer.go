@@ -0,0 +1,90 @@
+package hlx
+
+import "strings"
+
+// Tokenizer is an FTS5 tokenizer configuration, set with WithTokenizer.
+//
+// SQLite stores the tokenizer in the table definition when the index is
+// created, so an existing index keeps using it when reopened.
+type Tokenizer struct {
+	args []string
+}
+
+// Diacritics selects how the unicode61 tokenizer handles diacritics.
+type Diacritics int
+
+const (
+	// DiacriticsDefault uses the tokenizer default, DiacriticsRemove.
+	DiacriticsDefault Diacritics = iota
+	// DiacriticsKeep keeps diacritics, "café" doesn't match "cafe".
+	DiacriticsKeep
+	// DiacriticsRemove removes diacritics from Latin script characters,
+	// except when a character is composed of several code points.
+	DiacriticsRemove
+	// DiacriticsRemoveAll removes diacritics from Latin script characters,
+	// including the ones composed of several code points.
+	DiacriticsRemoveAll
+)
+
+// Unicode61Options configures the unicode61 tokenizer.
+type Unicode61Options struct {
+	Diacritics Diacritics
+	// TokenChars are characters considered part of tokens, in addition to
+	// letters and numbers.
+	TokenChars string
+	// Separators are characters that separate tokens, in addition to
+	// whitespace and punctuation.
+	Separators string
+}
+
+// Unicode61 returns the FTS5 default tokenizer, splitting text on
+// whitespace and punctuation according to Unicode 6.1.
+func Unicode61(opts Unicode61Options) Tokenizer {
+	args := []string{"unicode61"}
+	switch opts.Diacritics {
+	case DiacriticsKeep:
+		args = append(args, "remove_diacritics", "0")
+	case DiacriticsRemove:
+		args = append(args, "remove_diacritics", "1")
+	case DiacriticsRemoveAll:
+		args = append(args, "remove_diacritics", "2")
+	}
+	if opts.TokenChars != "" {
+		args = append(args, "tokenchars", quoteTokenizerArg(opts.TokenChars))
+	}
+	if opts.Separators != "" {
+		args = append(args, "separators", quoteTokenizerArg(opts.Separators))
+	}
+
+	return Tokenizer{args: args}
+}
+
+// Porter wraps base with the Porter stemmer, so English words match their
+// other inflections: "running" matches "run".
+func Porter(base Tokenizer) Tokenizer {
+	return Tokenizer{args: append([]string{"porter"}, base.args...)}
+}
+
+// Trigram returns the trigram tokenizer, which indexes every sequence of
+// three characters and enables substring matching. Queries need at least
+// three characters to match.
+func Trigram(caseSensitive bool) Tokenizer {
+	cs := "0"
+	if caseSensitive {
+		cs = "1"
+	}
+	return Tokenizer{args: []string{"trigram", "case_sensitive", cs}}
+}
+
+// String returns the value of the FTS5 tokenize option.
+func (t Tokenizer) String() string {
+	return strings.Join(t.args, " ")
+}
+
+func (t Tokenizer) isZero() bool {
+	return len(t.args) == 0
+}
+
+func quoteTokenizerArg(arg string) string {
+	return `'` + strings.ReplaceAll(arg, `'`, `''`) + `'`
+}
@@ -0,0 +1,69 @@
+package hlx
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenizerString(t *testing.T) {
+	assert.Equal(t, "unicode61", Unicode61(Unicode61Options{}).String())
+	assert.Equal(t, "porter unicode61 remove_diacritics 2",
+		Porter(Unicode61(Unicode61Options{Diacritics: DiacriticsRemoveAll})).String())
+	assert.Equal(t, `unicode61 remove_diacritics 0 tokenchars '-_' separators 'x"'''`,
+		Unicode61(Unicode61Options{Diacritics: DiacriticsKeep, TokenChars: "-_", Separators: `x"'`}).String())
+	assert.Equal(t, "trigram case_sensitive 1", Trigram(true).String())
+}
+
+func TestWithTokenizer(t *testing.T) {
+	tests := []struct {
+		name      string
+		tokenizer Tokenizer
+		text      string
+		query     string
+		matches   bool
+	}{
+		{"default does not stem", Tokenizer{}, "running", "run", false},
+		{"porter stems", Porter(Unicode61(Unicode61Options{})), "running", "run", true},
+		{"diacritics removed by default", Unicode61(Unicode61Options{}), "café", "cafe", true},
+		{"diacritics kept", Unicode61(Unicode61Options{Diacritics: DiacriticsKeep}), "café", "cafe", false},
+		{"diacritics removed", Unicode61(Unicode61Options{Diacritics: DiacriticsRemoveAll}), "café", "cafe", true},
+		{"tokenchars", Unicode61(Unicode61Options{TokenChars: "+"}), "c++ code", `"c++"`, true},
+		{"tokenchars keeps tokens whole", Unicode61(Unicode61Options{TokenChars: "+"}), "c++ code", "c", false},
+		{"separators", Unicode61(Unicode61Options{Separators: "x"}), "fooxbar", "bar", true},
+		{"trigram substring", Trigram(false), "Hello", "ELL", true},
+		{"trigram case sensitive", Trigram(true), "Hello", "ELL", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idx, err := NewIndex[TestDoc](":memory:", WithTokenizer(tt.tokenizer))
+			if err != nil {
+				t.Fatalf("Failed to create index: %v", err)
+			}
+			assert.NoError(t, idx.Insert(TestDoc{Title: tt.text}))
+
+			count, err := idx.Count(tt.query)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.matches, count == 1)
+		})
+	}
+}
+
+func TestTokenizerIsPersisted(t *testing.T) {
+	uri := fmt.Sprintf("file://%s", filepath.Join(t.TempDir(), "porter.db"))
+
+	idx, err := NewIndex[TestDoc](uri, WithTokenizer(Porter(Unicode61(Unicode61Options{}))))
+	assert.NoError(t, err)
+	assert.NoError(t, idx.Insert(TestDoc{Title: "running"}))
+
+	idx, err = NewIndex[TestDoc](uri)
+	assert.NoError(t, err)
+	assert.NoError(t, idx.Insert(TestDoc{Title: "jumping"}))
+
+	count, err := idx.Count("jump")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
@@ -0,0 +1,145 @@
+package hlx
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInsertDuplicateID(t *testing.T) {
+	idx, err := NewIndex[TestDoc](":memory:")
+	assert.NoError(t, err)
+	assert.NoError(t, idx.Insert(TestDoc{Id: "1", Title: "original"}))
+
+	err = idx.Insert(TestDoc{Id: "2", Title: "new"}, TestDoc{Id: "1", Title: "duplicate"})
+	assert.ErrorIs(t, err, ErrDuplicateID)
+	var docErr *DocumentError
+	assert.True(t, errors.As(err, &docErr))
+	assert.Equal(t, 1, docErr.Index)
+
+	results, err := idx.Search("original OR duplicate OR new")
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+
+	err = idx.BulkInsert(BestEffort, TestDoc{Id: "1", Title: "duplicate"}, TestDoc{Id: "2", Title: "new"})
+	assert.ErrorIs(t, err, ErrDuplicateID)
+	doc, err := idx.Get("2")
+	assert.NoError(t, err)
+	assert.Equal(t, "new", doc.Title)
+
+	t.Run("deleted ids can be reused", func(t *testing.T) {
+		assert.NoError(t, idx.Delete("1"))
+		_, err := idx.Get("1")
+		assert.ErrorIs(t, err, ErrDocumentNotFound)
+
+		assert.NoError(t, idx.Insert(TestDoc{Id: "1", Title: "reinserted"}))
+		doc, err := idx.Get("1")
+		assert.NoError(t, err)
+		assert.Equal(t, "reinserted", doc.Title)
+	})
+}
+
+func TestUpdate(t *testing.T) {
+	idx, err := NewIndex[TestDoc](":memory:")
+	assert.NoError(t, err)
+	assert.NoError(t, idx.Insert(TestDoc{Id: "1", Title: "before"}))
+
+	assert.NoError(t, idx.Update(TestDoc{Id: "1", Title: "after"}))
+	doc, err := idx.Get("1")
+	assert.NoError(t, err)
+	assert.Equal(t, "after", doc.Title)
+
+	results, err := idx.Search("before")
+	assert.NoError(t, err)
+	assert.Len(t, results, 0)
+	results, err = idx.Search("after")
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+
+	err = idx.Update(TestDoc{Id: "2", Title: "missing"})
+	assert.ErrorIs(t, err, ErrDocumentNotFound)
+	err = idx.Update(TestDoc{Title: "no id"})
+	assert.ErrorIs(t, err, ErrDocumentNotFound)
+}
+
+func TestUpsert(t *testing.T) {
+	idx, err := NewIndex[TestDoc](":memory:")
+	assert.NoError(t, err)
+	assert.NoError(t, idx.Insert(TestDoc{Id: "1", Title: "crawled"}))
+
+	err = idx.Upsert(
+		TestDoc{Id: "1", Title: "recrawled"},
+		TestDoc{Id: "2", Title: "discovered"},
+		TestDoc{Title: "anonymous"},
+	)
+	assert.NoError(t, err)
+
+	doc, err := idx.Get("1")
+	assert.NoError(t, err)
+	assert.Equal(t, "recrawled", doc.Title)
+
+	results, err := idx.Search("crawled OR recrawled OR discovered OR anonymous")
+	assert.NoError(t, err)
+	assert.Len(t, results, 3)
+
+	t.Run("is atomic", func(t *testing.T) {
+		type doc struct {
+			Id    string
+			Extra any
+		}
+		idx, err := NewIndex[doc](":memory:")
+		assert.NoError(t, err)
+		assert.NoError(t, idx.Insert(doc{Id: "1", Extra: "kept"}))
+
+		err = idx.Upsert(doc{Id: "1", Extra: "lost"}, doc{Id: "2", Extra: struct{}{}})
+		var docErr *DocumentError
+		assert.True(t, errors.As(err, &docErr))
+		assert.Equal(t, 1, docErr.Index)
+
+		d, err := idx.Get("1")
+		assert.NoError(t, err)
+		assert.Equal(t, "kept", d.Extra)
+	})
+}
+
+func TestLegacyIndexIDs(t *testing.T) {
+	dbfile := filepath.Join(t.TempDir(), "legacy.db")
+	db, err := sqlx.Open("sqlite3", dbfile)
+	assert.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec("CREATE VIRTUAL TABLE fulltext_search USING FTS5(id, title, description, content)")
+	assert.NoError(t, err)
+	_, err = db.Exec("INSERT INTO fulltext_search VALUES ('1', 'legacy', '', ''), ('2', 'document', '', ''), ('2', 'duplicated', '', '')")
+	assert.NoError(t, err)
+
+	idx, err := NewIndex[TestDoc]("", WithDB(db))
+	assert.NoError(t, err)
+
+	// The last copy of a duplicated id is kept.
+	doc, err := idx.Get("2")
+	assert.NoError(t, err)
+	assert.Equal(t, "duplicated", doc.Title)
+	results, err := idx.Search("document OR duplicated")
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+
+	err = idx.Insert(TestDoc{Id: "1"})
+	assert.ErrorIs(t, err, ErrDuplicateID)
+
+	assert.NoError(t, idx.Insert(TestDoc{Id: "3", Title: "new"}))
+	results, err = idx.Search("legacy OR duplicated OR new")
+	assert.NoError(t, err)
+	assert.Len(t, results, 3)
+
+	assert.NoError(t, idx.Delete("2"))
+	exists, err := idx.Exists("2")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+	results, err = idx.Search("document OR duplicated")
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+}